@@ -74,25 +74,24 @@ func TestApp_EdgeCases(t *testing.T) {
 		app.startup(context.Background())
 		
 		// Test ID generation with manual transfers to avoid runtime issues
-		app.transfers = []FileTransfer{
-			{ID: "test1", Name: "file1.txt", Status: FileTransferStatusCompleted},
-			{ID: "test2", Name: "file2.txt", Status: FileTransferStatusSending},
-			{ID: "test3", Name: "file3.txt", Status: FileTransferStatusReceiving},
-		}
-		
+		app.registry.Add(FileTransfer{ID: "test3", Name: "file3.txt", Status: FileTransferStatusReceiving})
+		app.registry.Add(FileTransfer{ID: "test2", Name: "file2.txt", Status: FileTransferStatusSending})
+		app.registry.Add(FileTransfer{ID: "test1", Name: "file1.txt", Status: FileTransferStatusCompleted})
+
 		if app.Len() != 3 {
 			t.Errorf("expected 3 transfers, got %d", app.Len())
 		}
-		
-		// Test ID generation
+
+		// getSendId/getReceiveId are numbered from a counter independent
+		// of len(transfers), so they don't reflect the 3 transfers above.
 		sendId := app.getSendId()
-		expectedSendId := "send-3"
+		expectedSendId := "send-0"
 		if sendId != expectedSendId {
 			t.Errorf("expected send ID %s, got %s", expectedSendId, sendId)
 		}
-		
+
 		receiveId := app.getReceiveId()
-		expectedReceiveId := "receive-3"
+		expectedReceiveId := "receive-0"
 		if receiveId != expectedReceiveId {
 			t.Errorf("expected receive ID %s, got %s", expectedReceiveId, receiveId)
 		}
@@ -136,8 +135,8 @@ func TestApp_EdgeCases(t *testing.T) {
 			Status:   FileTransferStatusPreparing,
 		}
 		
-		app.transfers = []FileTransfer{transfer}
-		
+		app.registry.Add(transfer)
+
 		if app.Len() != 1 {
 			t.Fatalf("expected 1 transfer, got %d", app.Len())
 		}
@@ -182,11 +181,11 @@ func TestApp_EdgeCases(t *testing.T) {
 			Status:   FileTransferStatusPreparing,
 		}
 		
-		app.transfers = []FileTransfer{transfer}
-		
+		app.registry.Add(transfer)
+
 		transfers := app.GetTransfers()
 		resultTransfer := transfers[0]
-		
+
 		if resultTransfer.Name != longName {
 			t.Errorf("transfer name mismatch: expected %s, got %s", longName, resultTransfer.Name)
 		}
@@ -202,7 +201,7 @@ func TestListFiles_EdgeCases(t *testing.T) {
 	t.Run("Empty directory", func(t *testing.T) {
 		tempDir := t.TempDir()
 		
-		files, err := listFiles(tempDir)
+		files, err := listFiles(osFS{}, tempDir)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -237,7 +236,7 @@ func TestListFiles_EdgeCases(t *testing.T) {
 			}
 		}
 		
-		files, err := listFiles(tempDir)
+		files, err := listFiles(osFS{}, tempDir)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -267,7 +266,7 @@ func TestListFiles_EdgeCases(t *testing.T) {
 	})
 	
 	t.Run("Nonexistent directory", func(t *testing.T) {
-		_, err := listFiles("/nonexistent/directory")
+		_, err := listFiles(osFS{}, "/nonexistent/directory")
 		if err == nil {
 			t.Error("expected error for nonexistent directory")
 		}
@@ -296,7 +295,7 @@ func TestGetFileDiff_EdgeCases(t *testing.T) {
 			t.Fatalf("failed to create large file 2: %v", err)
 		}
 		
-		diff, err := getFileDiff(file1, file2)
+		diff, err := getFileDiff(osFS{}, file1, file2)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -326,7 +325,7 @@ func TestGetFileDiff_EdgeCases(t *testing.T) {
 			t.Fatalf("failed to create binary file 2: %v", err)
 		}
 		
-		diff, err := getFileDiff(file1, file2)
+		diff, err := getFileDiff(osFS{}, file1, file2)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
@@ -343,12 +342,10 @@ func TestApp_ThreadSafety(t *testing.T) {
 	app.startup(context.Background())
 	
 	// Add some initial data
-	app.transfers = []FileTransfer{
-		{ID: "initial", Status: FileTransferStatusCompleted},
-	}
-	
-	done := make(chan bool, 3)
-	
+	app.registry.Add(FileTransfer{ID: "initial", Status: FileTransferStatusCompleted})
+
+	done := make(chan bool, 4)
+
 	// Goroutine 1: repeatedly call Len()
 	go func() {
 		for i := 0; i < 1000; i++ {
@@ -356,7 +353,7 @@ func TestApp_ThreadSafety(t *testing.T) {
 		}
 		done <- true
 	}()
-	
+
 	// Goroutine 2: repeatedly call GetTransfers()
 	go func() {
 		for i := 0; i < 1000; i++ {
@@ -364,17 +361,27 @@ func TestApp_ThreadSafety(t *testing.T) {
 		}
 		done <- true
 	}()
-	
+
 	// Goroutine 3: repeatedly call RespondToOverwrite
 	go func() {
 		for i := 0; i < 1000; i++ {
-			app.RespondToOverwrite("nonexistent", "yes")
+			app.RespondToOverwrite("nonexistent", "yes", false)
 		}
 		done <- true
 	}()
-	
+
+	// Goroutine 4: repeatedly append, the write Len()/GetTransfers() must
+	// never race against (a plain slice append can reallocate the backing
+	// array out from under a concurrent reader)
+	go func() {
+		for i := 0; i < 1000; i++ {
+			app.registry.Add(FileTransfer{ID: "concurrent"})
+		}
+		done <- true
+	}()
+
 	// Wait for all goroutines to complete
-	for i := 0; i < 3; i++ {
+	for i := 0; i < 4; i++ {
 		<-done
 	}
 }
@@ -386,7 +393,7 @@ func BenchmarkApp_Len(b *testing.B) {
 	
 	// Add some transfers
 	for i := 0; i < 100; i++ {
-		app.transfers = append(app.transfers, FileTransfer{ID: "test"})
+		app.registry.Add(FileTransfer{ID: "test"})
 	}
 	
 	b.ResetTimer()
@@ -401,7 +408,7 @@ func BenchmarkApp_GetTransfers(b *testing.B) {
 	
 	// Add some transfers
 	for i := 0; i < 100; i++ {
-		app.transfers = append(app.transfers, FileTransfer{ID: "test"})
+		app.registry.Add(FileTransfer{ID: "test"})
 	}
 	
 	b.ResetTimer()
@@ -415,6 +422,6 @@ func BenchmarkListFiles(b *testing.B) {
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		listFiles(tempDir)
+		listFiles(osFS{}, tempDir)
 	}
 }
\ No newline at end of file
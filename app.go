@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/schollz/croc/v10/src/croc"
@@ -22,6 +25,27 @@ type FileTransfer struct {
 	Progress int                `json:"progress"`
 	Status   FileTransferStatus `json:"status"`
 	Code     string             `json:"code,omitempty"`
+	Verified bool               `json:"verified"`
+	Hash     string             `json:"hash,omitempty"`
+	Manifest []ManifestEntry    `json:"manifest,omitempty"`
+
+	// RelayName is the resolved relay this transfer used (or will use),
+	// recorded so checkQueueAndQuota can enforce TransferPolicy's
+	// PerPeerQuotaBytes against everything currently queued for the same
+	// relay.
+	RelayName string `json:"relayName,omitempty"`
+
+	// PreviewDataURI is a small thumbnail of the transferred file, as a
+	// data: URI, if preview.GenerateDataURI recognized its content (see
+	// thumbnail.go). Empty for anything it doesn't have a Generator for.
+	PreviewDataURI string `json:"previewDataUri,omitempty"`
+
+	// TransportUsed records which configured Transport (see transport.go)
+	// completed this transfer, e.g. "wormhole" or "lan". Empty for
+	// transfers made through the legacy SendFile/ReceiveFile path, which
+	// always uses the wormhole relay directly rather than a configurable
+	// transport chain.
+	TransportUsed string `json:"transportUsed,omitempty"`
 }
 
 type OverwritePrompt struct {
@@ -30,16 +54,57 @@ type OverwritePrompt struct {
 	OldSize    int64  `json:"oldSize"`
 	NewSize    int64  `json:"newSize"`
 	Diff       string `json:"diff"`
+
+	// RemainingCount is how many more files in this transfer still need an
+	// overwrite decision after this one, so the UI can offer "apply to
+	// remaining N files" alongside a yes/no answer.
+	RemainingCount int `json:"remainingCount"`
 }
 
 // App struct
 type (
 	App struct {
-		ctx                context.Context
-		transfers          []FileTransfer
-		overwriteResponses map[string]chan string
-
+		ctx             context.Context
+		registry        *transferRegistry
+		hashAlgorithm   string
+		overwritePolicy OverwritePolicy
+		endpoints       *relayStore
+		resumable       *resumeStore
+		fs              FS
+		configPath      string
+		configMu        sync.Mutex
+		policy          TransferPolicy
+		policyPath      string
+		policyMu        sync.Mutex
+
+		// transportMu guards transports and transportTimeout, the pluggable
+		// transport chain SendFile/ReceiveFile try in order via
+		// tryTransportsForSend/tryTransportsForReceive (see transport.go).
+		// Empty transports falls back to a single wormholeTransport,
+		// matching SendFile/ReceiveFile's prior always-wormhole behavior.
+		transportMu      sync.Mutex
+		transports       []Transport
+		transportTimeout time.Duration
+
+		// sync.RWMutex guards hashAlgorithm and overwritePolicy. The
+		// transfer list, overwrite-response channels, and per-transfer
+		// overwrite-policy overrides live in registry, which owns its own
+		// mutex instead.
 		sync.RWMutex
+
+		statsMu     sync.Mutex
+		statsGroups map[string]*statsGroup
+
+		controlMu sync.Mutex
+		controls  map[string]*transferControl
+
+		// emit sends a Wails runtime event. It defaults to
+		// runtime.EventsEmit in startup, and exists as a field rather than
+		// calling runtime.EventsEmit directly so tests exercising
+		// event-emitting methods can stub it out instead of needing a ctx
+		// rooted in a real Wails lifecycle context, which runtime.EventsEmit
+		// fatally exits the process without.
+		emit func(ctx context.Context, eventName string, optionalData ...interface{})
 	}
 
 	FileTransferStatus string
@@ -53,230 +118,718 @@ const (
 
 	FileTransferStatusError     FileTransferStatus = "error"
 	FileTransferStatusCompleted FileTransferStatus = "completed"
+
+	FileTransferStatusPaused    FileTransferStatus = "paused"
+	FileTransferStatusCancelled FileTransferStatus = "cancelled"
+
+	// FileTransferStatusResumable marks a receive transfer that was
+	// interrupted mid-stream but left enough state behind (see resume.go)
+	// for ResumeInterruptedTransfer to continue it from its last
+	// acknowledged offset instead of starting over.
+	FileTransferStatusResumable FileTransferStatus = "resumable"
+
+	// FileTransferStatusRejected marks a transfer the configured
+	// TransferPolicy (see policy.go) refused to queue or accept, with the
+	// reason available as the error SendFile/SendFiles/ReceiveFile returned
+	// or, on the receive side, logged at the point of rejection.
+	FileTransferStatusRejected FileTransferStatus = "rejected"
 )
 
 const (
 	TransferEventUpdated   string = "transfer:updated"
 	TransferEventOverwrite string = "transfer:overwrite"
+	TransferEventVerified  string = "transfer:verified"
+	TransferEventStats     string = "transfer:stats"
+
+	// TransferEventFileProgress carries FileProgressEvent payloads for
+	// batch transfers, keyed by both transfer ID and manifest file index.
+	TransferEventFileProgress string = "transfer:file-progress"
+
+	// TransferEventPreview carries PreviewEvent payloads, emitted once a
+	// received file's thumbnail (see thumbnail.go) is available, before the
+	// transfer as a whole finishes verification.
+	TransferEventPreview string = "transfer:preview"
 )
 
+// PreviewEvent carries a generated thumbnail for one file of a transfer,
+// emitted on TransferEventPreview.
+type PreviewEvent struct {
+	TransferID     string `json:"transferId"`
+	FileName       string `json:"fileName"`
+	PreviewDataURI string `json:"previewDataUri"`
+}
+
+// defaultHashAlgorithm matches the algorithm croc.Options used to hardcode;
+// it's kept as the default so existing transfers behave unchanged.
+const defaultHashAlgorithm = "xxhash"
+
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
-	a.transfers = make([]FileTransfer, 0)
-	a.overwriteResponses = make(map[string]chan string)
+	a.registry = newTransferRegistry()
+	a.hashAlgorithm = defaultHashAlgorithm
+	a.statsGroups = make(map[string]*statsGroup)
+	a.controls = make(map[string]*transferControl)
+	if a.emit == nil {
+		a.emit = runtime.EventsEmit
+	}
+	if a.fs == nil {
+		a.fs = osFS{}
+	}
+	cfg := defaultConfig()
+	if path, err := configFilePath(); err != nil {
+		logrus.WithError(err).Warn("failed to resolve user config dir, falling back to the default config")
+	} else {
+		a.configPath = path
+		if loaded, err := loadPersistedConfig(path); err != nil {
+			logrus.WithError(err).Warn("failed to load persisted config, falling back to the default config")
+		} else {
+			cfg = loaded
+		}
+	}
+	a.ctx = WithConfig(a.ctx, &cfg)
+
+	store, err := newRelayStore()
+	if err != nil {
+		logrus.WithError(err).Warn("failed to load relay config, falling back to the default relay")
+		store = &relayStore{relays: map[string]RelayEndpoint{defaultRelay.Name: defaultRelay}, current: defaultRelay.Name}
+	}
+	a.endpoints = store
+
+	resumable, err := newResumeStore()
+	if err != nil {
+		logrus.WithError(err).Warn("failed to load resumable transfer state, starting with none")
+		resumable = &resumeStore{states: make(map[string]ResumableState)}
+	}
+	a.resumable = resumable
+
+	policy := defaultTransferPolicy()
+	if path, err := policyFilePath(); err != nil {
+		logrus.WithError(err).Warn("failed to resolve user config dir, falling back to an unrestricted transfer policy")
+	} else {
+		a.policyPath = path
+		if loaded, err := loadPersistedPolicy(path); err != nil {
+			logrus.WithError(err).Warn("failed to load persisted transfer policy, falling back to unrestricted")
+		} else {
+			policy = loaded
+		}
+	}
+	a.policy = policy
+}
+
+// GetTransferStats returns the live accounting stats for a transfer, or a
+// zero value if no stats have been recorded for it yet.
+func (a *App) GetTransferStats(id string) TransferStats {
+	a.statsMu.Lock()
+	group, ok := a.statsGroups[id]
+	a.statsMu.Unlock()
+
+	if !ok {
+		return TransferStats{}
+	}
+	return group.snapshot()
+}
+
+// GetAllStats returns stats for every transfer with recorded accounting
+// data, plus a "global" aggregate (keyed by globalStatsID) summed across
+// all of them, similar to rclone's default stats group.
+func (a *App) GetAllStats() map[string]TransferStats {
+	a.statsMu.Lock()
+	groups := make(map[string]*statsGroup, len(a.statsGroups))
+	for id, g := range a.statsGroups {
+		groups[id] = g
+	}
+	a.statsMu.Unlock()
+
+	all := make(map[string]TransferStats, len(groups)+1)
+	var global TransferStats
+	for id, g := range groups {
+		stats := g.snapshot()
+		all[id] = stats
+		global.BytesTransferred += stats.BytesTransferred
+		global.TotalBytes += stats.TotalBytes
+		global.CurrentBps += stats.CurrentBps
+		if stats.PeakBps > global.PeakBps {
+			global.PeakBps = stats.PeakBps
+		}
+	}
+	all[globalStatsID] = global
+	return all
+}
+
+// trackStats registers a stats group for transferID and samples it at
+// transferStatsSampleHz until stop is closed, emitting a TransferEventStats
+// event after every sample so the frontend can render live throughput.
+func (a *App) trackStats(ctx context.Context, transferID string, stop <-chan struct{}, sample func() (bytesTransferred, totalBytes int64)) {
+	group := newStatsGroup()
+
+	a.statsMu.Lock()
+	if a.statsGroups == nil {
+		a.statsGroups = make(map[string]*statsGroup)
+	}
+	a.statsGroups[transferID] = group
+	a.statsMu.Unlock()
+
+	emit := func() {
+		bytesTransferred, totalBytes := sample()
+		group.update(bytesTransferred, totalBytes)
+		a.emit(ctx, TransferEventStats, TransferStatsEvent{TransferID: transferID, Stats: group.snapshot()})
+	}
+
+	ticker := time.NewTicker(time.Second / transferStatsSampleHz)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			emit()
+			return
+		case <-ticker.C:
+			emit()
+		}
+	}
+}
+
+// SetHashAlgorithm selects the hash algorithm used both to verify received
+// files and to advertise file hashes when sending. Pass "sha256" for a
+// paranoid mode that trades speed for a cryptographic hash.
+func (a *App) SetHashAlgorithm(algorithm string) error {
+	if !supportedHashAlgorithms[algorithm] {
+		return errors.Errorf("unsupported hash algorithm: %s", algorithm)
+	}
+
+	a.Lock()
+	a.hashAlgorithm = algorithm
+	a.Unlock()
+
+	return nil
+}
+
+// getHashAlgorithm returns the configured hash algorithm, falling back to
+// the default if none has been set yet.
+func (a *App) getHashAlgorithm() string {
+	a.RLock()
+	defer a.RUnlock()
+
+	if a.hashAlgorithm == "" {
+		return defaultHashAlgorithm
+	}
+	return a.hashAlgorithm
+}
+
+// SetOverwritePolicy sets the default overwrite policy the receive loop
+// consults before ever emitting TransferEventOverwrite, for transfers that
+// have no per-transfer override. See SetTransferOverwritePolicy for a
+// single-transfer override, and RespondToOverwrite's applyToAll parameter
+// for setting one from a dialog response.
+func (a *App) SetOverwritePolicy(policy OverwritePolicy) error {
+	if !validOverwritePolicies[policy] {
+		return errors.Errorf("unsupported overwrite policy: %s", policy)
+	}
+
+	a.Lock()
+	a.overwritePolicy = policy
+	a.Unlock()
+
+	return nil
 }
 
+// getOverwritePolicy returns the configured global overwrite policy,
+// falling back to OverwritePolicyAsk if none has been set yet.
+func (a *App) getOverwritePolicy() OverwritePolicy {
+	a.RLock()
+	defer a.RUnlock()
+
+	if a.overwritePolicy == "" {
+		return OverwritePolicyAsk
+	}
+	return a.overwritePolicy
+}
+
+// SetTransferOverwritePolicy sets an overwrite-policy override for a single
+// transfer, taking precedence over the App's global policy for the rest of
+// that transfer's receive loop.
+func (a *App) SetTransferOverwritePolicy(transferID string, policy OverwritePolicy) error {
+	if !validOverwritePolicies[policy] {
+		return errors.Errorf("unsupported overwrite policy: %s", policy)
+	}
+
+	a.registry.SetOverwritePolicy(transferID, policy)
+	return nil
+}
+
+// effectiveOverwritePolicy returns the policy performReceive should consult
+// for transferID: its per-transfer override if one was set, otherwise the
+// App's global policy.
+func (a *App) effectiveOverwritePolicy(transferID string) OverwritePolicy {
+	if policy, ok := a.registry.OverwritePolicy(transferID); ok {
+		return policy
+	}
+	return a.getOverwritePolicy()
+}
+
+// GetConfig returns the transfer configuration currently in effect: the
+// Config attached to a.ctx by the most recent SetConfig call, or the
+// persisted/default one startup loaded if SetConfig was never called.
+func (a *App) GetConfig() Config {
+	return *GetConfig(a.ctx)
+}
+
+// SetConfig replaces the relay/curve/overwrite/local-network settings every
+// subsequently started transfer reads from a.ctx, and persists it to
+// configFilePath so it's still in effect after a restart. Transfers already
+// in flight keep whatever Config they captured when they started. Pass an
+// empty HashAlgorithm/Curve to keep the current hash algorithm/p256 curve.
+func (a *App) SetConfig(cfg Config) error {
+	if cfg.HashAlgorithm != "" {
+		if err := a.SetHashAlgorithm(cfg.HashAlgorithm); err != nil {
+			return err
+		}
+	}
+	if cfg.Curve == "" {
+		cfg.Curve = "p256"
+	}
+
+	a.configMu.Lock()
+	a.ctx = WithConfig(a.ctx, &cfg)
+	path := a.configPath
+	a.configMu.Unlock()
+
+	if path == "" {
+		return nil
+	}
+	return savePersistedConfig(path, cfg)
+}
+
+// buildOptions derives a croc.Options for operation ("send" or "receive")
+// from cfg, resolving the relay endpoint from relayName (falling back to
+// cfg.RelayName, then the configured default for operation).
+func (a *App) buildOptions(cfg *Config, relayName, operation string, isSender bool, sharedSecret string) croc.Options {
+	if relayName == "" {
+		relayName = cfg.RelayName
+	}
+	endpoint := a.resolveRelay(relayName, operation)
+
+	return croc.Options{
+		IsSender:       isSender,
+		SharedSecret:   sharedSecret,
+		Debug:          cfg.Debug,
+		NoPrompt:       true,
+		RelayAddress:   endpoint.Address,
+		RelayPorts:     endpoint.Ports,
+		RelayPassword:  endpoint.Password,
+		NoMultiplexing: false,
+		DisableLocal:   cfg.DisableLocal,
+		OnlyLocal:      cfg.OnlyLocal,
+		IgnoreStdin:    true,
+		Overwrite:      cfg.Overwrite,
+		Curve:          cfg.Curve,
+		HashAlgorithm:  a.getHashAlgorithm(),
+		NoCompress:     cfg.DisableCompression,
+	}
+}
+
+// SendFile sends filePath over the default relay. Use SendFileViaRelay to
+// pick a specific configured relay by name.
 func (a *App) SendFile(filePath string) (string, error) {
+	return a.SendFileViaRelay(filePath, "")
+}
+
+// SendFileViaRelay sends filePath, routing through the named relay instead
+// of the configured default. Pass an empty relayName to use the default.
+func (a *App) SendFileViaRelay(filePath, relayName string) (string, error) {
+	return a.sendFile(filePath, relayName, nil)
+}
+
+// SendFileWithConfig sends filePath using overrides instead of the
+// Config bound to a.ctx, deriving a scoped context for just this
+// transfer's goroutine so it never mutates App-wide state. This is what
+// lets concurrent transfers use different relay or OnlyLocal settings.
+func (a *App) SendFileWithConfig(filePath string, overrides Config) (string, error) {
+	return a.sendFile(filePath, overrides.RelayName, &overrides)
+}
+
+// sendFile is the shared implementation behind SendFile, SendFileViaRelay
+// and SendFileWithConfig. cfg is nil unless the caller supplied explicit
+// overrides, in which case performSend reads from a scoped context instead
+// of a.ctx.
+func (a *App) sendFile(filePath, relayName string, cfg *Config) (string, error) {
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
 		return "", errors.Wrapf(err, "failed to stat file: %s", filePath)
 	}
 
+	effectiveRelayName := relayName
+	if effectiveRelayName == "" && cfg != nil {
+		effectiveRelayName = cfg.RelayName
+	}
+	resolvedRelay := a.resolveRelay(effectiveRelayName, "send").Name
+
 	transfer := FileTransfer{
-		ID:       a.getSendId(),
-		Name:     fileInfo.Name(),
-		Files:    []string{fileInfo.Name()},
-		Size:     fileInfo.Size(),
-		Progress: 0,
-		Status:   FileTransferStatusPreparing,
+		ID:        a.getSendId(),
+		Name:      fileInfo.Name(),
+		Files:     []string{fileInfo.Name()},
+		Size:      fileInfo.Size(),
+		Progress:  0,
+		Status:    FileTransferStatusPreparing,
+		RelayName: resolvedRelay,
 	}
 
-	a.Lock()
-	a.transfers = append([]FileTransfer{transfer}, a.transfers...)
-	a.Unlock()
+	if err := a.checkSendPolicy(fileInfo.Name(), fileInfo.Size(), resolvedRelay); err != nil {
+		transfer.Status = FileTransferStatusRejected
+		a.registry.Add(transfer)
+		return "", err
+	}
+
+	if uri, ok := generatePreview(osFS{}, filePath); ok {
+		transfer.PreviewDataURI = uri
+	}
 
-	go a.performSend(&a.transfers[0], filePath)
+	stored := a.registry.Add(transfer)
+
+	ctx := a.ctx
+	if cfg != nil {
+		ctx = WithConfig(ctx, cfg)
+	}
+
+	go a.performSend(ctx, stored, filePath, relayName)
 
 	return transfer.ID, nil
 }
 
-func (a *App) performSend(transfer *FileTransfer, filePath string) {
-	transfer.Code = utils.GetRandomName()
+// performSend offers filePath over relayName's transport chain (see
+// a.getTransports), falling back to the next configured Transport if one
+// errors or times out. The wormhole relay (the default chain, when
+// SetTransports was never called) assigns its own code once Offer
+// succeeds, so unlike the old direct-croc implementation, transfer.Code
+// isn't known until then.
+func (a *App) performSend(ctx context.Context, transfer *FileTransfer, filePath, relayName string) {
+	ctx, cancel := context.WithCancel(ctx)
+	ctl := a.registerControl(transfer.ID, cancel)
+	defer a.unregisterControl(transfer.ID)
+	defer cancel()
+
 	transfer.Status = FileTransferStatusWaiting
-	runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
+	transfer.Progress = 0
+	a.emit(ctx, TransferEventUpdated, transfer)
 
-	options := croc.Options{
-		IsSender:       true,
-		SharedSecret:   transfer.Code,
-		Debug:          false,
-		NoPrompt:       true,
-		RelayAddress:   "croc.schollz.com:9009",
-		RelayPorts:     []string{"9009", "9010", "9011", "9012", "9013"},
-		RelayPassword:  "pass123",
-		NoMultiplexing: false,
-		DisableLocal:   false,
-		OnlyLocal:      false,
-		IgnoreStdin:    true,
-		Overwrite:      true,
-		Curve:          "p256",
-		HashAlgorithm:  "xxhash",
+	if !a.checkpoint(ctx, ctl, transfer) {
+		return
 	}
 
-	crocClient, err := croc.New(options)
+	var sent, total atomic.Int64
+	total.Store(transfer.Size)
+	statsStop := make(chan struct{})
+	defer close(statsStop)
+	go a.trackStats(ctx, transfer.ID, statsStop, func() (int64, int64) {
+		return sent.Load(), total.Load()
+	})
+
+	transport, code, err := tryTransportsForSend(ctx, a.getTransports(relayName), a.getTransportTimeout(), filePath, func(t Transport, p Progress) {
+		sent.Store(p.BytesTransferred)
+		if p.TotalBytes > 0 {
+			total.Store(p.TotalBytes)
+		}
+		transfer.TransportUsed = t.Name()
+		transfer.Status = FileTransferStatusSending
+		if p.TotalBytes > 0 {
+			transfer.Progress = int(p.BytesTransferred * 100 / p.TotalBytes)
+		}
+		a.emit(ctx, TransferEventUpdated, transfer)
+	})
 	if err != nil {
-		logrus.WithError(err).Error("error while creating croc client")
+		logrus.WithError(err).Error("every configured transport failed to send")
 		transfer.Status = FileTransferStatusError
-		runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
+		a.emit(ctx, TransferEventUpdated, transfer)
 		return
 	}
 
-	filesInfo, emptyFolders, totalFolders, err := croc.GetFilesInfo(
-		[]string{filePath},
-		false,
-		false,
-		[]string{},
-	)
+	transfer.TransportUsed = transport.Name()
+	transfer.Code = code
+	transfer.Status = FileTransferStatusCompleted
+	transfer.Progress = 100
+	a.emit(ctx, TransferEventUpdated, transfer)
+}
+
+// SendFiles packages multiple files and/or directories into a single croc
+// session sharing one code phrase, instead of SendFile's one-transfer-
+// per-file model. The returned FileTransfer carries a per-file Manifest
+// (path, size, sha, index) so a receiver can render progress per file;
+// SendFile/SendFileViaRelay remain the legacy single-file path for
+// callers that don't understand a manifest.
+func (a *App) SendFiles(paths []string) (FileTransfer, error) {
+	if err := resolveSendPaths(paths); err != nil {
+		return FileTransfer{}, err
+	}
+
+	filesInfo, emptyFolders, totalFolders, err := croc.GetFilesInfo(paths, false, false, []string{})
 	if err != nil {
-		logrus.WithError(err).Error("error while getting files info")
-		transfer.Status = FileTransferStatusError
-		runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
-		return
+		return FileTransfer{}, errors.Wrap(err, "failed to resolve files to send")
+	}
+	if len(filesInfo) == 0 {
+		return FileTransfer{}, errors.New("no files to send")
+	}
+
+	manifest, err := buildManifest(filesInfo, a.getHashAlgorithm())
+	if err != nil {
+		return FileTransfer{}, err
 	}
 
 	var fileNames []string
+	var totalSize int64
 	for _, f := range filesInfo {
 		fileNames = append(fileNames, f.Name)
+		totalSize += f.Size
+	}
+
+	name := fmt.Sprintf("%d files", len(fileNames))
+	if len(fileNames) == 1 {
+		name = fileNames[0]
+	}
+
+	resolvedRelay := a.resolveRelay("", "send").Name
+
+	transfer := FileTransfer{
+		ID:        a.getSendId(),
+		Name:      name,
+		Files:     fileNames,
+		Size:      totalSize,
+		Progress:  0,
+		Status:    FileTransferStatusPreparing,
+		Manifest:  manifest,
+		RelayName: resolvedRelay,
+	}
+
+	if err := a.checkSendFilesPolicy(filesInfo, totalSize, resolvedRelay); err != nil {
+		transfer.Status = FileTransferStatusRejected
+		stored := a.registry.Add(transfer)
+		return *stored, err
+	}
+
+	stored := a.registry.Add(transfer)
+
+	go a.performSendBatch(a.ctx, stored, filesInfo, emptyFolders, totalFolders)
+
+	return transfer, nil
+}
+
+func (a *App) performSendBatch(ctx context.Context, transfer *FileTransfer, filesInfo, emptyFolders []croc.FileInfo, totalFolders int) {
+	ctx, cancel := context.WithCancel(ctx)
+	ctl := a.registerControl(transfer.ID, cancel)
+	defer a.unregisterControl(transfer.ID)
+	defer cancel()
+
+	transfer.Code = utils.GetRandomName()
+	transfer.Status = FileTransferStatusWaiting
+	a.emit(ctx, TransferEventUpdated, transfer)
+
+	options := a.buildOptions(GetConfig(ctx), "", "send", true, transfer.Code)
+
+	crocClient, err := croc.New(options)
+	if err != nil {
+		logrus.WithError(err).Error("error while creating croc client")
+		transfer.Status = FileTransferStatusError
+		a.emit(ctx, TransferEventUpdated, transfer)
+		return
+	}
+
+	transfer.Progress = 0
+	a.emit(ctx, TransferEventUpdated, transfer)
+
+	statsStop := make(chan struct{})
+	defer close(statsStop)
+	go a.trackStats(ctx, transfer.ID, statsStop, func() (int64, int64) {
+		return crocClient.TotalSent, transfer.Size
+	})
+
+	fileProgressStop := make(chan struct{})
+	defer close(fileProgressStop)
+	go a.trackFileProgress(ctx, transfer.ID, fileProgressStop, func() (int, int64, int64) {
+		idx := crocClient.FilesToTransferCurrentNum
+		if idx < 0 || idx >= len(crocClient.FilesToTransfer) {
+			return idx, 0, 0
+		}
+		return idx, crocClient.TotalSent, crocClient.FilesToTransfer[idx].Size
+	})
+
+	if !a.checkpoint(ctx, ctl, transfer) {
+		return
 	}
-	transfer.Files = fileNames
-	transfer.Progress = 0 // Set progress to 0% for the "waiting" state
-	runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
 
 	err = crocClient.Send(filesInfo, emptyFolders, totalFolders)
 	if err != nil {
 		logrus.WithError(err).Error("error sending files")
 		transfer.Status = FileTransferStatusError
-		runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
+		a.emit(ctx, TransferEventUpdated, transfer)
 		return
 	}
 
 	transfer.Status = FileTransferStatusCompleted
 	transfer.Progress = 100
-	runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
+	a.emit(ctx, TransferEventUpdated, transfer)
+}
+
+// trackFileProgress samples per-file progress for a batch transfer at
+// transferStatsSampleHz and emits TransferEventFileProgress, mirroring
+// trackStats but keyed by manifest file index instead of the transfer-wide
+// total.
+func (a *App) trackFileProgress(ctx context.Context, transferID string, stop <-chan struct{}, sample func() (fileIndex int, bytesSent, fileSize int64)) {
+	emit := func() {
+		index, sent, size := sample()
+		progress := 0
+		if size > 0 {
+			progress = int(float64(sent) / float64(size) * 100)
+		}
+		a.emit(ctx, TransferEventFileProgress, FileProgressEvent{
+			TransferID: transferID,
+			FileIndex:  index,
+			Progress:   progress,
+		})
+	}
+
+	ticker := time.NewTicker(time.Second / transferStatsSampleHz)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			emit()
+			return
+		case <-ticker.C:
+			emit()
+		}
+	}
 }
 
 func (a *App) getSendId() string {
-	return fmt.Sprintf("send-%d", a.Len())
+	return a.registry.NextSendID()
 }
 
 func (a *App) getReceiveId() string {
-	return fmt.Sprintf("receive-%d", a.Len())
+	return a.registry.NextReceiveID()
 }
 
 func (a *App) GetTransfers() []FileTransfer {
-	a.RLock()
-	defer a.RUnlock()
-
-	return a.transfers
+	return a.registry.Snapshot()
 }
 
 // Len returns the number of transfers in history
 func (a *App) Len() int {
-	a.RLock()
-	defer a.RUnlock()
-
-	return len(a.transfers)
+	return a.registry.Len()
 }
 
+// ReceiveFile receives code over the default relay. Use ReceiveFileViaRelay
+// to pick a specific configured relay by name.
 func (a *App) ReceiveFile(code, destinationPath string) (string, error) {
+	return a.ReceiveFileViaRelay(code, destinationPath, "")
+}
+
+// ReceiveFileViaRelay receives code, routing through the named relay
+// instead of the configured default. Pass an empty relayName to use the
+// default.
+func (a *App) ReceiveFileViaRelay(code, destinationPath, relayName string) (string, error) {
 	transfer := FileTransfer{
-		ID:       a.getReceiveId(),
-		Code:     code,
-		Progress: 0,
-		Status:   FileTransferStatusPreparing,
-		Name:     "Preparing to receive...",
-		Files:    []string{},
+		ID:        a.getReceiveId(),
+		Code:      code,
+		Progress:  0,
+		Status:    FileTransferStatusPreparing,
+		Name:      "Preparing to receive...",
+		Files:     []string{},
+		RelayName: a.resolveRelay(relayName, "receive").Name,
 	}
 
-	a.Lock()
-	a.transfers = append([]FileTransfer{transfer}, a.transfers...)
-	a.Unlock()
+	stored := a.registry.Add(transfer)
 
-	go a.performReceive(&a.transfers[0], code, destinationPath)
+	go a.performReceive(a.ctx, stored, code, destinationPath, relayName, "")
 
 	return transfer.ID, nil
 }
 
-func (a *App) performReceive(transfer *FileTransfer, code, destinationPath string) {
+// performReceive accepts code over relayName's transport chain (see
+// a.getTransports) into a scratch tempDir, falling back to the next
+// configured Transport if one errors or times out. resumeTempDir is empty
+// for a fresh transfer; ResumeInterruptedTransfer passes the tempDir a
+// prior interrupted attempt left behind instead, so whichever Transport
+// handles the retry picks up where that attempt left off rather than
+// re-downloading from scratch (the wormhole relay relies on croc's own
+// chunk-resume logic, which consults whatever's already on disk at the
+// path it's about to write - Accept is passed tempDir as its
+// destinationPath for exactly this reason).
+func (a *App) performReceive(ctx context.Context, transfer *FileTransfer, code, destinationPath, relayName, resumeTempDir string) {
+	ctx, cancel := context.WithCancel(ctx)
+	ctl := a.registerControl(transfer.ID, cancel)
+	defer a.unregisterControl(transfer.ID)
+	defer cancel()
+
 	transfer.Status = FileTransferStatusReceiving
 	transfer.Name = "Receiving..."
-	runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
+	a.emit(ctx, TransferEventUpdated, transfer)
 
-	currentDir, err := os.Getwd()
-	if err != nil {
-		logrus.WithError(err).Error("error getting current directory")
-		transfer.Status = FileTransferStatusError
-		runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
-		return
+	tempDir := resumeTempDir
+	var err error
+	if tempDir == "" {
+		tempDir, err = a.fs.TempDir("", "krokodyl-")
 	}
-
-	err = os.Chdir(destinationPath)
 	if err != nil {
-		logrus.WithError(err).Errorf("error changing directory to %s", destinationPath)
+		logrus.WithError(err).Error("error creating temp directory")
 		transfer.Status = FileTransferStatusError
-		runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
+		a.emit(ctx, TransferEventUpdated, transfer)
 		return
 	}
+	keepTempDir := false
 	defer func() {
-		os.Chdir(currentDir)
+		if !keepTempDir {
+			a.fs.RemoveAll(tempDir)
+		}
 	}()
 
-	options := croc.Options{
-		IsSender:       false,
-		SharedSecret:   code,
-		Debug:          false,
-		NoPrompt:       true,
-		RelayAddress:   "croc.schollz.com:9009",
-		RelayPorts:     []string{"9009", "9010", "9011", "9012", "9013"},
-		RelayPassword:  "pass123",
-		NoMultiplexing: false,
-		DisableLocal:   false,
-		OnlyLocal:      false,
-		IgnoreStdin:    true,
-		Overwrite:      true,
-		Curve:          "p256",
-		HashAlgorithm:  "xxhash",
-	}
-
-	tempDir, err := os.MkdirTemp("", "krokodyl-")
-	if err != nil {
-		logrus.WithError(err).Error("error creating temp directory")
-		transfer.Status = FileTransferStatusError
-		runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
+	if !a.checkpoint(ctx, ctl, transfer) {
 		return
 	}
-	defer os.RemoveAll(tempDir)
 
-	// Change to the temporary directory to receive the file
-	if err := os.Chdir(tempDir); err != nil {
-		logrus.WithError(err).Errorf("error changing to temp directory %s", tempDir)
-		transfer.Status = FileTransferStatusError
-		runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
-		return
-	}
+	var sent, total atomic.Int64
+	statsStop := make(chan struct{})
+	defer close(statsStop)
+	go a.trackStats(ctx, transfer.ID, statsStop, func() (int64, int64) {
+		return sent.Load(), total.Load()
+	})
 
-	crocClient, err := croc.New(options)
+	transport, final, err := tryTransportsForReceive(ctx, a.getTransports(relayName), a.getTransportTimeout(), code, tempDir, func(t Transport, p Progress) {
+		sent.Store(p.BytesTransferred)
+		if p.TotalBytes > 0 {
+			total.Store(p.TotalBytes)
+		}
+		transfer.TransportUsed = t.Name()
+		if p.TotalBytes > 0 {
+			transfer.Progress = int(p.BytesTransferred * 100 / p.TotalBytes)
+		}
+		a.emit(ctx, TransferEventUpdated, transfer)
+	})
 	if err != nil {
-		logrus.WithError(err).Error("error creating croc client")
-		transfer.Status = FileTransferStatusError
-		runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
-		return
-	}
-
-	if err := crocClient.Receive(); err != nil {
 		logrus.WithError(err).Error("error receiving files")
+		if state, ok := a.buildResumableState(transfer, code, destinationPath, relayName, tempDir); ok {
+			if putErr := a.resumable.put(state); putErr != nil {
+				logrus.WithError(putErr).Warn("failed to persist resumable transfer state")
+			} else {
+				keepTempDir = true
+				transfer.Status = FileTransferStatusResumable
+				a.emit(ctx, TransferEventUpdated, transfer)
+				return
+			}
+		}
 		transfer.Status = FileTransferStatusError
-		runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
+		a.emit(ctx, TransferEventUpdated, transfer)
 		return
 	}
+	transfer.TransportUsed = transport.Name()
 
 	// Now that the file is in the temp directory, get its info
-	receivedFileInfos, err := listFiles(tempDir)
+	receivedFileInfos, err := listFiles(a.fs, tempDir)
 	if err != nil {
 		logrus.WithError(err).Error("error listing files in temp directory")
 		transfer.Status = FileTransferStatusError
-		runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
+		a.emit(ctx, TransferEventUpdated, transfer)
 		return
 	}
 
@@ -288,59 +841,137 @@ func (a *App) performReceive(transfer *FileTransfer, code, destinationPath strin
 	if len(receivedFiles) == 0 {
 		logrus.Error("no files received")
 		transfer.Status = FileTransferStatusError
-		runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
+		a.emit(ctx, TransferEventUpdated, transfer)
 		return
 	}
 
+	algorithm := a.getHashAlgorithm()
+	allVerified := true
+	for _, fileInfo := range receivedFiles {
+		sourcePath := filepath.Join(tempDir, fileInfo.Name())
+
+		if err := a.checkReceivedFile(sourcePath, fileInfo.Name(), fileInfo.Size()); err != nil {
+			logrus.WithError(err).Errorf("received file %s rejected by transfer policy", fileInfo.Name())
+			transfer.Status = FileTransferStatusRejected
+			a.emit(ctx, TransferEventUpdated, transfer)
+			return
+		}
+
+		// croc's Receive() is a single blocking call with no mid-stream
+		// hook, so this is the earliest point a thumbnail can be generated:
+		// once the whole file has landed in tempDir, but still before hash
+		// verification and the move to its final destination.
+		if uri, ok := generatePreview(a.fs, sourcePath); ok {
+			if transfer.PreviewDataURI == "" {
+				transfer.PreviewDataURI = uri
+			}
+			a.emit(ctx, TransferEventPreview, PreviewEvent{
+				TransferID:     transfer.ID,
+				FileName:       fileInfo.Name(),
+				PreviewDataURI: uri,
+			})
+		}
+
+		hash, ok := expectedHash(final.Files, fileInfo.Name())
+		if !ok {
+			logrus.Warnf("no sender-reported hash for %s, skipping verification", fileInfo.Name())
+			allVerified = false
+			continue
+		}
+
+		if err := verifyFileHash(sourcePath, algorithm, hash); err != nil {
+			logrus.WithError(err).Error("received file failed hash verification")
+			transfer.Status = FileTransferStatusError
+			a.emit(ctx, TransferEventUpdated, transfer)
+			return
+		}
+
+		if transfer.Hash == "" {
+			transfer.Hash = hex.EncodeToString(hash)
+		}
+	}
+	// Only report a transfer as Verified if every file in it actually had a
+	// sender-reported hash checked: a Transport like lanTransport that
+	// doesn't populate Progress.Files (see transport.go) means verification
+	// was silently skipped above, and that must not look the same as an
+	// actually-verified transfer.
+	transfer.Verified = allVerified
+	if allVerified {
+		a.emit(ctx, TransferEventVerified, transfer)
+	}
+
 	var fileNames []string
 	var totalSize int64
-	for _, fileInfo := range receivedFiles {
+	for i, fileInfo := range receivedFiles {
+		if !a.checkpoint(ctx, ctl, transfer) {
+			return
+		}
+
 		sourcePath := filepath.Join(tempDir, fileInfo.Name())
 		destPath := filepath.Join(destinationPath, fileInfo.Name())
 
 		// Check if the file already exists
-		if existingInfo, err := os.Stat(destPath); err == nil {
-			// File exists, prompt for overwrite
-			responseChan := make(chan string)
-			a.Lock()
-			a.overwriteResponses[transfer.ID] = responseChan
-			a.Unlock()
-
-			diff, err := getFileDiff(destPath, sourcePath)
-			if err != nil {
-				logrus.WithError(err).Warnf("could not get file diff")
-				diff = "Could not generate file difference."
-			}
+		if existingInfo, err := a.fs.Stat(destPath); err == nil {
+			policy := a.effectiveOverwritePolicy(transfer.ID)
 
-			runtime.EventsEmit(a.ctx, TransferEventOverwrite, OverwritePrompt{
-				TransferID: transfer.ID,
-				FileName:   fileInfo.Name(),
-				OldSize:    existingInfo.Size(),
-				NewSize:    fileInfo.Size(),
-				Diff:       diff,
-			})
-
-			// Wait for the user's response
-			response := <-responseChan
-			if response != "yes" {
-				// User chose not to overwrite, so we skip this file
-				logrus.Infof("User chose not to overwrite %s", fileInfo.Name())
+			switch {
+			case policy == OverwritePolicyAlwaysSkip:
+				logrus.Infof("overwrite policy %s: skipping %s", policy, fileInfo.Name())
 				continue // Move to the next file
+
+			case policy.picksNewName():
+				renamed, err := nonCollidingName(a.fs, destinationPath, fileInfo.Name())
+				if err != nil {
+					logrus.WithError(err).Errorf("failed to pick a non-colliding name for %s", fileInfo.Name())
+					transfer.Status = FileTransferStatusError
+					a.emit(ctx, TransferEventUpdated, transfer)
+					return
+				}
+				destPath = filepath.Join(destinationPath, renamed)
+
+			case policy == OverwritePolicyAlwaysOverwrite:
+				// Fall through and overwrite destPath below.
+
+			default: // OverwritePolicyAsk
+				responseChan := a.registry.RegisterOverwriteResponse(transfer.ID)
+
+				diff, err := getFileDiff(a.fs, destPath, sourcePath)
+				if err != nil {
+					logrus.WithError(err).Warnf("could not get file diff")
+					diff = "Could not generate file difference."
+				}
+
+				a.emit(ctx, TransferEventOverwrite, OverwritePrompt{
+					TransferID:     transfer.ID,
+					FileName:       fileInfo.Name(),
+					OldSize:        existingInfo.Size(),
+					NewSize:        fileInfo.Size(),
+					Diff:           diff,
+					RemainingCount: len(receivedFiles) - i - 1,
+				})
+
+				// Wait for the user's response
+				response := <-responseChan
+				if response != "yes" {
+					// User chose not to overwrite, so we skip this file
+					logrus.Infof("User chose not to overwrite %s", fileInfo.Name())
+					continue // Move to the next file
+				}
 			}
 		}
 
 		// Ensure the destination directory exists
-		if err := os.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
+		if err := a.fs.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
 			logrus.WithError(err).Errorf("failed to create destination directory for %s", destPath)
 			transfer.Status = FileTransferStatusError
-			runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
+			a.emit(ctx, TransferEventUpdated, transfer)
 			return
 		}
 
-		if err := os.Rename(sourcePath, destPath); err != nil {
+		if err := a.fs.Rename(sourcePath, destPath); err != nil {
 			logrus.WithError(err).Errorf("failed to move file from %s to %s", sourcePath, destPath)
 			transfer.Status = FileTransferStatusError
-			runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
+			a.emit(ctx, TransferEventUpdated, transfer)
 			return
 		}
 
@@ -361,12 +992,16 @@ func (a *App) performReceive(transfer *FileTransfer, code, destinationPath strin
 	transfer.Size = totalSize
 	transfer.Status = FileTransferStatusCompleted
 	transfer.Progress = 100
-	runtime.EventsEmit(a.ctx, TransferEventUpdated, transfer)
+	a.emit(ctx, TransferEventUpdated, transfer)
+
+	if err := a.resumable.remove(transfer.ID); err != nil {
+		logrus.WithError(err).Warn("failed to clear resumable transfer state after a successful receive")
+	}
 }
 
-func listFiles(dir string) (map[string]os.FileInfo, error) {
+func listFiles(fsys FS, dir string) (map[string]os.FileInfo, error) {
 	files := make(map[string]os.FileInfo)
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	err := fsys.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -402,17 +1037,20 @@ func (a *App) SelectDirectory() (string, error) {
 	return selection, nil
 }
 
-func (a *App) RespondToOverwrite(transferID string, response string) {
-	a.RLock()
-	responseChan, ok := a.overwriteResponses[transferID]
-	a.RUnlock()
-
-	if ok {
-		responseChan <- response
-		a.Lock()
-		delete(a.overwriteResponses, transferID)
-		a.Unlock()
+// RespondToOverwrite answers a pending TransferEventOverwrite prompt for
+// transferID. If applyToAll is set, response is also installed as a
+// per-transfer overwrite-policy override (OverwritePolicyAlwaysOverwrite for
+// "yes", OverwritePolicyAlwaysSkip otherwise), so the remaining files in
+// that transfer are resolved without further prompts.
+func (a *App) RespondToOverwrite(transferID string, response string, applyToAll bool) {
+	if applyToAll {
+		policy := OverwritePolicyAlwaysSkip
+		if response == "yes" {
+			policy = OverwritePolicyAlwaysOverwrite
+		}
+		a.registry.SetOverwritePolicy(transferID, policy)
 	}
+	a.registry.RespondToOverwrite(transferID, response)
 }
 
 func (a *App) GetDefaultDownloadPath() (string, error) {
@@ -422,30 +1060,3 @@ func (a *App) GetDefaultDownloadPath() (string, error) {
 	}
 	return filepath.Join(homeDir, "Downloads"), nil
 }
-
-func getFileDiff(file1, file2 string) (string, error) {
-	f1, err := os.ReadFile(file1)
-	if err != nil {
-		return "", err
-	}
-	f2, err := os.ReadFile(file2)
-	if err != nil {
-		return "", err
-	}
-
-	// For simplicity, we'll just return a basic line-by-line comparison
-	// In a real app, you might use a proper diffing library
-	diff := ""
-	lines1 := string(f1)
-	lines2 := string(f2)
-
-	if lines1 == lines2 {
-		return "Files are identical.", nil
-	}
-
-	diff += "--- a/" + filepath.Base(file1) + "\n"
-	diff += "+++ b/" + filepath.Base(file2) + "\n"
-	diff += "File content differs."
-
-	return diff, nil
-}
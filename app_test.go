@@ -4,7 +4,6 @@ import (
 	"context"
 	"os"
 	"path/filepath"
-	"strings"
 	"testing"
 	"time"
 )
@@ -20,14 +19,11 @@ func TestApp_startup(t *testing.T) {
 	if app.ctx == nil {
 		t.Error("ctx should be set after startup")
 	}
-	if app.transfers == nil {
-		t.Error("transfers should be initialized")
+	if app.registry == nil {
+		t.Error("registry should be initialized")
 	}
-	if app.overwriteResponses == nil {
-		t.Error("overwriteResponses should be initialized")
-	}
-	if len(app.transfers) != 0 {
-		t.Errorf("transfers should be empty, got %d", len(app.transfers))
+	if app.Len() != 0 {
+		t.Errorf("transfers should be empty, got %d", app.Len())
 	}
 }
 
@@ -42,11 +38,9 @@ func TestApp_Len(t *testing.T) {
 	}
 	
 	// Add some transfers manually
-	app.transfers = []FileTransfer{
-		{ID: "test1", Status: FileTransferStatusCompleted},
-		{ID: "test2", Status: FileTransferStatusSending},
-	}
-	
+	app.registry.Add(FileTransfer{ID: "test1", Status: FileTransferStatusCompleted})
+	app.registry.Add(FileTransfer{ID: "test2", Status: FileTransferStatusSending})
+
 	if app.Len() != 2 {
 		t.Errorf("expected length 2, got %d", app.Len())
 	}
@@ -63,13 +57,15 @@ func TestApp_GetTransfers(t *testing.T) {
 		t.Errorf("expected 0 transfers, got %d", len(transfers))
 	}
 	
-	// Add some transfers
+	// Add some transfers, in reverse so the registry's newest-first
+	// ordering lines up with testTransfers
 	testTransfers := []FileTransfer{
 		{ID: "test1", Name: "file1.txt", Status: FileTransferStatusCompleted},
 		{ID: "test2", Name: "file2.txt", Status: FileTransferStatusSending},
 	}
-	app.transfers = testTransfers
-	
+	app.registry.Add(testTransfers[1])
+	app.registry.Add(testTransfers[0])
+
 	transfers = app.GetTransfers()
 	if len(transfers) != 2 {
 		t.Errorf("expected 2 transfers, got %d", len(transfers))
@@ -91,17 +87,16 @@ func TestApp_getSendId(t *testing.T) {
 	app := &App{}
 	app.startup(context.Background())
 	
-	// Test with no transfers
+	// IDs are numbered from the count of sends started, not len(transfers),
+	// so they stay unique even if transfers are later removed.
 	id := app.getSendId()
 	expected := "send-0"
 	if id != expected {
 		t.Errorf("expected %s, got %s", expected, id)
 	}
-	
-	// Add some transfers and test again
-	app.transfers = []FileTransfer{{ID: "test1"}, {ID: "test2"}}
+
 	id = app.getSendId()
-	expected = "send-2"
+	expected = "send-1"
 	if id != expected {
 		t.Errorf("expected %s, got %s", expected, id)
 	}
@@ -112,17 +107,16 @@ func TestApp_getReceiveId(t *testing.T) {
 	app := &App{}
 	app.startup(context.Background())
 	
-	// Test with no transfers
+	// IDs are numbered from the count of receives started, not
+	// len(transfers), so they stay unique even if transfers are removed.
 	id := app.getReceiveId()
 	expected := "receive-0"
 	if id != expected {
 		t.Errorf("expected %s, got %s", expected, id)
 	}
-	
-	// Add some transfers and test again
-	app.transfers = []FileTransfer{{ID: "test1"}, {ID: "test2"}}
+
 	id = app.getReceiveId()
-	expected = "receive-2"
+	expected = "receive-1"
 	if id != expected {
 		t.Errorf("expected %s, got %s", expected, id)
 	}
@@ -156,17 +150,16 @@ func TestApp_RespondToOverwrite(t *testing.T) {
 	transferID := "test-transfer"
 	
 	// Test with non-existent transfer ID (should not panic)
-	app.RespondToOverwrite(transferID, "yes")
-	
+	app.RespondToOverwrite(transferID, "yes", false)
+
 	// Test with existing transfer ID
-	responseChan := make(chan string, 1)
-	app.overwriteResponses[transferID] = responseChan
-	
+	responseChan := app.registry.RegisterOverwriteResponse(transferID)
+
 	// Respond in a goroutine to avoid blocking
 	go func() {
-		app.RespondToOverwrite(transferID, "yes")
+		app.RespondToOverwrite(transferID, "yes", false)
 	}()
-	
+
 	// Wait for response with timeout
 	select {
 	case response := <-responseChan:
@@ -176,9 +169,9 @@ func TestApp_RespondToOverwrite(t *testing.T) {
 	case <-time.After(1 * time.Second):
 		t.Error("timeout waiting for response")
 	}
-	
+
 	// Verify the channel is cleaned up
-	if _, exists := app.overwriteResponses[transferID]; exists {
+	if _, exists := app.registry.overwriteResponses[transferID]; exists {
 		t.Error("response channel should be cleaned up after response")
 	}
 }
@@ -252,6 +245,8 @@ func TestFileTransferStatus(t *testing.T) {
 		{FileTransferStatusReceiving, "receiving"},
 		{FileTransferStatusError, "error"},
 		{FileTransferStatusCompleted, "completed"},
+		{FileTransferStatusPaused, "paused"},
+		{FileTransferStatusCancelled, "cancelled"},
 	}
 	
 	for _, test := range tests {
@@ -304,7 +299,7 @@ func TestListFiles(t *testing.T) {
 	}
 	
 	// Test listFiles
-	files, err := listFiles(tempDir)
+	files, err := listFiles(osFS{}, tempDir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -431,67 +426,3 @@ func TestOverwritePrompt(t *testing.T) {
 		t.Errorf("Diff mismatch: expected 'test diff', got %s", prompt.Diff)
 	}
 }
-
-// TestGetFileDiff tests the getFileDiff function
-func TestGetFileDiff(t *testing.T) {
-	tempDir := t.TempDir()
-	
-	// Create two test files with different content
-	file1 := filepath.Join(tempDir, "file1.txt")
-	file2 := filepath.Join(tempDir, "file2.txt")
-	
-	content1 := "Hello, World!"
-	content2 := "Hello, Universe!"
-	
-	err := os.WriteFile(file1, []byte(content1), 0644)
-	if err != nil {
-		t.Fatalf("failed to create file1: %v", err)
-	}
-	
-	err = os.WriteFile(file2, []byte(content2), 0644)
-	if err != nil {
-		t.Fatalf("failed to create file2: %v", err)
-	}
-	
-	// Test with different files
-	diff, err := getFileDiff(file1, file2)
-	if err != nil {
-		t.Errorf("unexpected error: %v", err)
-	}
-	
-	if diff == "" {
-		t.Error("diff should not be empty for different files")
-	}
-	
-	// Verify diff contains expected headers
-	if !strings.Contains(diff, "--- a/file1.txt") {
-		t.Error("diff should contain file1 header")
-	}
-	
-	if !strings.Contains(diff, "+++ b/file2.txt") {
-		t.Error("diff should contain file2 header")
-	}
-	
-	// Test with identical files
-	diff2, err := getFileDiff(file1, file1)
-	if err != nil {
-		t.Errorf("unexpected error: %v", err)
-	}
-	
-	expectedMsg := "Files are identical."
-	if diff2 != expectedMsg {
-		t.Errorf("expected '%s', got '%s'", expectedMsg, diff2)
-	}
-	
-	// Test with non-existent file (first file)
-	_, err = getFileDiff("/nonexistent/file.txt", file1)
-	if err == nil {
-		t.Error("expected error for non-existent first file")
-	}
-	
-	// Test with non-existent file (second file)
-	_, err = getFileDiff(file1, "/nonexistent/file.txt")
-	if err == nil {
-		t.Error("expected error for non-existent second file")
-	}
-}
\ No newline at end of file
@@ -24,12 +24,8 @@ func TestMain_Function(t *testing.T) {
 		t.Error("app context should be set")
 	}
 	
-	if app.transfers == nil {
-		t.Error("transfers should be initialized")
-	}
-	
-	if app.overwriteResponses == nil {
-		t.Error("overwriteResponses should be initialized")
+	if app.registry == nil {
+		t.Error("registry should be initialized")
 	}
 }
 
@@ -251,25 +247,19 @@ func TestApp_MultipleInitialization(t *testing.T) {
 	// Note: contexts from context.Background() are typically the same instance
 	// so we can't reliably test context change this way
 	
-	// Check that transfers and responses are reinitialized (both should be empty)
-	if len(app.transfers) != 0 {
+	// Check that the registry is reinitialized (should be empty)
+	if app.Len() != 0 {
 		t.Error("transfers should be empty after reinitialization")
 	}
-	if len(app.overwriteResponses) != 0 {
-		t.Error("overwriteResponses should be empty after reinitialization")
-	}
-	
+
 	// Verify new state is correct
 	if app.ctx == nil {
 		t.Error("context should be set after second startup")
 	}
-	if app.transfers == nil {
-		t.Error("transfers should be initialized after second startup")
-	}
-	if app.overwriteResponses == nil {
-		t.Error("overwriteResponses should be initialized after second startup")
+	if app.registry == nil {
+		t.Error("registry should be initialized after second startup")
 	}
-	if len(app.transfers) != 0 {
+	if app.Len() != 0 {
 		t.Error("transfers should be empty after reinitialization")
 	}
 }
\ No newline at end of file
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Config holds the per-transfer croc settings that performSend and
+// performReceive used to duplicate as two copies of the same hardcoded
+// croc.Options literal. It's attached to a context.Context rather than
+// kept as global/App state, following rclone's deglobalise-config refactor
+// (PR #4685, fs.GetConfig(ctx)/fs.AddConfig(ctx)) — this lets a single
+// transfer goroutine run with overrides via SendFileWithConfig without
+// disturbing the settings any other in-flight transfer reads. The App's
+// own default, set via SetConfig, is additionally persisted to disk so it
+// survives a restart; see configFilePath/loadPersistedConfig. Which relay a
+// transfer uses is configured separately, through the EndpointSource in
+// relay.go, rather than duplicated here.
+type Config struct {
+	// RelayName picks a configured relay by name, the same way the
+	// relayName parameter to SendFileViaRelay/ReceiveFileViaRelay does.
+	// Empty means "use the configured default relay for this operation".
+	RelayName string `json:"relayName,omitempty"`
+
+	Curve              string `json:"curve"`
+	HashAlgorithm      string `json:"hashAlgorithm"`
+	Overwrite          bool   `json:"overwrite"`
+	DisableLocal       bool   `json:"disableLocal"`
+	OnlyLocal          bool   `json:"onlyLocal"`
+	DisableCompression bool   `json:"disableCompression"`
+	Debug              bool   `json:"debug"`
+}
+
+// defaultConfig mirrors the croc.Options literal every transfer hardcoded
+// before Config existed.
+func defaultConfig() Config {
+	return Config{
+		Curve:         "p256",
+		HashAlgorithm: defaultHashAlgorithm,
+		Overwrite:     true,
+	}
+}
+
+// configFileName is the name of the JSON file persisted under the user's
+// config dir, alongside relay.go's relays.json.
+const configFileName = "config.json"
+
+// configFilePath resolves the path SetConfig persists to and startup loads
+// from, mirroring newRelayStore's use of os.UserConfigDir.
+func configFilePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to resolve user config dir")
+	}
+	return filepath.Join(configDir, "krokodyl", configFileName), nil
+}
+
+// loadPersistedConfig reads the Config persisted at path, falling back to
+// defaultConfig if the file doesn't exist yet.
+func loadPersistedConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return defaultConfig(), nil
+		}
+		return Config{}, errors.Wrapf(err, "failed to read config: %s", path)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, errors.Wrapf(err, "failed to parse config: %s", path)
+	}
+	return cfg, nil
+}
+
+// savePersistedConfig writes cfg as JSON to path, creating its parent
+// directory if needed.
+func savePersistedConfig(path string, cfg Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrapf(err, "failed to create config dir for %s", path)
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal config")
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return errors.Wrapf(err, "failed to write config: %s", path)
+	}
+	return nil
+}
+
+// configContextKey is the unexported context key Config is stored under,
+// so it can't collide with keys from other packages.
+type configContextKey struct{}
+
+// WithConfig returns a copy of ctx carrying cfg, retrievable with GetConfig.
+func WithConfig(ctx context.Context, cfg *Config) context.Context {
+	return context.WithValue(ctx, configContextKey{}, cfg)
+}
+
+// GetConfig returns the Config attached to ctx by WithConfig, or a
+// defaultConfig if ctx has none (e.g. a bare context.Background() in a
+// test that didn't go through App.startup).
+func GetConfig(ctx context.Context) *Config {
+	if cfg, ok := ctx.Value(configContextKey{}).(*Config); ok {
+		return cfg
+	}
+	cfg := defaultConfig()
+	return &cfg
+}
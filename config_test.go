@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestGetConfig_DefaultWithoutWithConfig tests that a bare context (as a
+// test that skips App.startup would have) falls back to defaultConfig
+// instead of returning nil.
+func TestGetConfig_DefaultWithoutWithConfig(t *testing.T) {
+	got := GetConfig(context.Background())
+	want := defaultConfig()
+	if *got != want {
+		t.Errorf("expected default config %+v, got %+v", want, *got)
+	}
+}
+
+// TestWithConfig_RoundTrip tests that GetConfig returns the exact Config
+// pointer WithConfig attached.
+func TestWithConfig_RoundTrip(t *testing.T) {
+	cfg := &Config{RelayName: "school", Curve: "p256", OnlyLocal: true}
+	ctx := WithConfig(context.Background(), cfg)
+
+	got := GetConfig(ctx)
+	if got != cfg {
+		t.Errorf("expected GetConfig to return the attached pointer, got a different one")
+	}
+}
+
+// TestApp_SetConfig tests that SetConfig fills in defaults for a
+// blank Curve, rejects an unsupported HashAlgorithm, and is reflected by
+// a.ctx afterwards.
+func TestApp_SetConfig(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+
+	if err := app.SetConfig(Config{OnlyLocal: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := GetConfig(app.ctx)
+	if cfg.Curve != "p256" {
+		t.Errorf("expected blank Curve to default to p256, got %q", cfg.Curve)
+	}
+	if !cfg.OnlyLocal {
+		t.Error("expected OnlyLocal to be carried through")
+	}
+
+	if err := app.SetConfig(Config{HashAlgorithm: "not-a-real-algorithm"}); err == nil {
+		t.Error("expected an error for an unsupported hash algorithm")
+	}
+}
+
+// TestApp_buildOptions_UsesContextConfig tests that buildOptions pulls
+// Curve/Overwrite/DisableLocal/OnlyLocal/DisableCompression/Debug from the
+// Config attached to the context it's given, rather than hardcoding them.
+func TestApp_buildOptions_UsesContextConfig(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+
+	cfg := &Config{Curve: "siec", Overwrite: false, DisableLocal: true, OnlyLocal: true, DisableCompression: true, Debug: true}
+	options := app.buildOptions(cfg, "", "send", true, "secret")
+
+	if options.Curve != "siec" {
+		t.Errorf("expected Curve siec, got %s", options.Curve)
+	}
+	if options.Overwrite {
+		t.Error("expected Overwrite false")
+	}
+	if !options.DisableLocal || !options.OnlyLocal || !options.Debug {
+		t.Error("expected DisableLocal/OnlyLocal/Debug to come from cfg")
+	}
+	if !options.NoCompress {
+		t.Error("expected NoCompress to come from cfg.DisableCompression")
+	}
+	if options.SharedSecret != "secret" || !options.IsSender {
+		t.Error("expected SharedSecret/IsSender to come from the explicit arguments")
+	}
+}
+
+// TestLoadPersistedConfig_DefaultsWithoutFile tests that loading from a
+// non-existent path falls back to defaultConfig instead of erroring.
+func TestLoadPersistedConfig_DefaultsWithoutFile(t *testing.T) {
+	cfg, err := loadPersistedConfig(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg != defaultConfig() {
+		t.Errorf("expected default config, got %+v", cfg)
+	}
+}
+
+// TestSavePersistedConfig_RoundTrip tests that a saved config reloads with
+// the same values.
+func TestSavePersistedConfig_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	want := Config{Curve: "siec", OnlyLocal: true, DisableCompression: true}
+
+	if err := savePersistedConfig(path, want); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+
+	got, err := loadPersistedConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading: %v", err)
+	}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+// TestApp_GetConfig_SetConfig_Persists tests that GetConfig reflects the
+// last SetConfig call and that it's written to the App's configPath.
+func TestApp_GetConfig_SetConfig_Persists(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+	app.configPath = filepath.Join(t.TempDir(), "config.json")
+
+	if err := app.SetConfig(Config{OnlyLocal: true, DisableCompression: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg := app.GetConfig(); !cfg.OnlyLocal || !cfg.DisableCompression {
+		t.Errorf("expected GetConfig to reflect the new settings, got %+v", cfg)
+	}
+
+	persisted, err := loadPersistedConfig(app.configPath)
+	if err != nil {
+		t.Fatalf("unexpected error loading persisted config: %v", err)
+	}
+	if !persisted.OnlyLocal || !persisted.DisableCompression {
+		t.Errorf("expected persisted config to reflect the new settings, got %+v", persisted)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// transferControl is the pause/cancel handle for one in-flight transfer.
+// croc.Client.Send/Receive are single blocking calls with no context
+// parameter and no exported Close, so there's no hook to interrupt them
+// mid-chunk from outside this package. Pause and cancel instead take
+// effect at the checkpoints performSend/performSendBatch/performReceive
+// already pass through between steps (before dialing the relay, between
+// files in a batch, before moving a received file out of the tempdir).
+type transferControl struct {
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	paused     bool
+	resume     chan struct{}
+	pauseState FileTransferStatus
+}
+
+func newTransferControl(cancel context.CancelFunc) *transferControl {
+	return &transferControl{cancel: cancel, resume: make(chan struct{})}
+}
+
+// pause marks the control paused, remembering resumeState so resumeTransfer
+// can restore it.
+func (c *transferControl) pause(resumeState FileTransferStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		return
+	}
+	c.paused = true
+	c.pauseState = resumeState
+	c.resume = make(chan struct{})
+}
+
+// resumeTransfer un-pauses the control, releasing anything blocked in wait,
+// and returns the status it should be restored to.
+func (c *transferControl) resumeTransfer() FileTransferStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.paused {
+		return c.pauseState
+	}
+	c.paused = false
+	close(c.resume)
+	return c.pauseState
+}
+
+// wait blocks until the control is resumed or ctx is cancelled, returning
+// ctx.Err() in the latter case. It's a no-op if the control isn't paused.
+func (c *transferControl) wait(ctx context.Context) error {
+	c.mu.Lock()
+	paused := c.paused
+	resume := c.resume
+	c.mu.Unlock()
+
+	if !paused {
+		return nil
+	}
+
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// registerControl creates and stores the transferControl for an in-flight
+// transfer, guarded by controlMu the same way statsMu guards statsGroups.
+func (a *App) registerControl(id string, cancel context.CancelFunc) *transferControl {
+	ctl := newTransferControl(cancel)
+
+	a.controlMu.Lock()
+	if a.controls == nil {
+		a.controls = make(map[string]*transferControl)
+	}
+	a.controls[id] = ctl
+	a.controlMu.Unlock()
+
+	return ctl
+}
+
+func (a *App) unregisterControl(id string) {
+	a.controlMu.Lock()
+	delete(a.controls, id)
+	a.controlMu.Unlock()
+}
+
+func (a *App) getControl(id string) (*transferControl, bool) {
+	a.controlMu.Lock()
+	defer a.controlMu.Unlock()
+
+	ctl, ok := a.controls[id]
+	return ctl, ok
+}
+
+// checkpoint blocks on ctl's pause state and reports whether the transfer
+// should continue. If ctx was cancelled (directly or while paused), it
+// marks transfer as FileTransferStatusCancelled and returns false.
+func (a *App) checkpoint(ctx context.Context, ctl *transferControl, transfer *FileTransfer) bool {
+	if err := ctl.wait(ctx); err != nil {
+		transfer.Status = FileTransferStatusCancelled
+		a.emit(ctx, TransferEventUpdated, transfer)
+		return false
+	}
+	return true
+}
+
+// PauseTransfer pauses an in-flight transfer at its next checkpoint.
+// croc exposes no mid-chunk hook, so a transfer already inside
+// crocClient.Send/Receive only actually pauses once that call returns or
+// between files in a batch; PauseTransfer still reports the transfer as
+// FileTransferStatusPaused immediately so the frontend reflects intent.
+func (a *App) PauseTransfer(id string) error {
+	ctl, ok := a.getControl(id)
+	if !ok {
+		return errors.Errorf("no in-flight transfer with id %s", id)
+	}
+	transfer, ok := a.registry.Get(id)
+	if !ok {
+		return errors.Errorf("no in-flight transfer with id %s", id)
+	}
+
+	ctl.pause(transfer.Status)
+	transfer.Status = FileTransferStatusPaused
+	a.emit(a.ctx, TransferEventUpdated, transfer)
+	return nil
+}
+
+// ResumeTransfer resumes a transfer paused with PauseTransfer, restoring
+// whatever status it had before pausing.
+func (a *App) ResumeTransfer(id string) error {
+	ctl, ok := a.getControl(id)
+	if !ok {
+		return errors.Errorf("no in-flight transfer with id %s", id)
+	}
+	transfer, ok := a.registry.Get(id)
+	if !ok {
+		return errors.Errorf("no in-flight transfer with id %s", id)
+	}
+
+	transfer.Status = ctl.resumeTransfer()
+	a.emit(a.ctx, TransferEventUpdated, transfer)
+	return nil
+}
+
+// CancelTransfer cancels an in-flight transfer's context and marks it
+// FileTransferStatusCancelled. As with PauseTransfer, a transfer blocked
+// inside crocClient.Send/Receive only unwinds once that call returns an
+// error from its underlying connection, since croc doesn't check ctx
+// itself; CancelTransfer still updates status immediately.
+func (a *App) CancelTransfer(id string) error {
+	ctl, ok := a.getControl(id)
+	if !ok {
+		return errors.Errorf("no in-flight transfer with id %s", id)
+	}
+	transfer, ok := a.registry.Get(id)
+	if !ok {
+		return errors.Errorf("no in-flight transfer with id %s", id)
+	}
+
+	ctl.cancel()
+	ctl.resumeTransfer() // unblock anything waiting in checkpoint so it observes ctx.Done
+	transfer.Status = FileTransferStatusCancelled
+	a.emit(a.ctx, TransferEventUpdated, transfer)
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// TestTransferControl_PauseWaitResume tests that wait blocks while paused
+// and releases once resumeTransfer is called.
+func TestTransferControl_PauseWaitResume(t *testing.T) {
+	ctl := newTransferControl(func() {})
+	ctl.pause(FileTransferStatusSending)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ctl.wait(context.Background())
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("wait returned before resumeTransfer was called")
+	default:
+	}
+
+	if status := ctl.resumeTransfer(); status != FileTransferStatusSending {
+		t.Errorf("expected resumeTransfer to restore FileTransferStatusSending, got %s", status)
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error from wait: %v", err)
+	}
+}
+
+// TestTransferControl_WaitUnpaused tests that wait is a no-op when the
+// control was never paused.
+func TestTransferControl_WaitUnpaused(t *testing.T) {
+	ctl := newTransferControl(func() {})
+	if err := ctl.wait(context.Background()); err != nil {
+		t.Errorf("unexpected error from wait on an unpaused control: %v", err)
+	}
+}
+
+// TestTransferControl_WaitCancelled tests that wait unblocks with ctx's
+// error when the transfer's context is cancelled while paused.
+func TestTransferControl_WaitCancelled(t *testing.T) {
+	ctl := newTransferControl(func() {})
+	ctl.pause(FileTransferStatusReceiving)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := ctl.wait(ctx); err == nil {
+		t.Error("expected wait to return an error for a cancelled context")
+	}
+}
+
+// TestApp_PauseResumeTransfer tests that PauseTransfer/ResumeTransfer update
+// transfer status and unblock a goroutine waiting on the checkpoint.
+func TestApp_PauseResumeTransfer(t *testing.T) {
+	app := &App{emit: func(context.Context, string, ...interface{}) {}}
+	app.startup(context.Background())
+
+	transfer := FileTransfer{ID: "send-0", Status: FileTransferStatusSending}
+	app.registry.Add(transfer)
+	ctl := app.registerControl(transfer.ID, func() {})
+
+	if err := app.PauseTransfer(transfer.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stored, _ := app.registry.Get(transfer.ID)
+	if stored.Status != FileTransferStatusPaused {
+		t.Errorf("expected status paused, got %s", stored.Status)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ctl.wait(app.ctx)
+	}()
+
+	if err := app.ResumeTransfer(transfer.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored.Status != FileTransferStatusSending {
+		t.Errorf("expected status restored to sending, got %s", stored.Status)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error from wait: %v", err)
+	}
+}
+
+// TestApp_CancelTransfer tests that CancelTransfer marks the transfer
+// cancelled and cancels its context.
+func TestApp_CancelTransfer(t *testing.T) {
+	app := &App{emit: func(context.Context, string, ...interface{}) {}}
+	app.startup(context.Background())
+
+	transfer := FileTransfer{ID: "send-0", Status: FileTransferStatusSending}
+	app.registry.Add(transfer)
+
+	ctx, cancel := context.WithCancel(app.ctx)
+	app.registerControl(transfer.ID, cancel)
+
+	if err := app.CancelTransfer(transfer.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stored, _ := app.registry.Get(transfer.ID)
+	if stored.Status != FileTransferStatusCancelled {
+		t.Errorf("expected status cancelled, got %s", stored.Status)
+	}
+	if ctx.Err() == nil {
+		t.Error("expected the transfer's context to be cancelled")
+	}
+}
+
+// TestApp_PauseTransfer_Unknown tests that controlling an unknown transfer
+// ID returns an error instead of panicking.
+func TestApp_PauseTransfer_Unknown(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+
+	if err := app.PauseTransfer("nonexistent"); err == nil {
+		t.Error("expected an error for an unknown transfer id")
+	}
+	if err := app.ResumeTransfer("nonexistent"); err == nil {
+		t.Error("expected an error for an unknown transfer id")
+	}
+	if err := app.CancelTransfer("nonexistent"); err == nil {
+		t.Error("expected an error for an unknown transfer id")
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// diffMaxFileSize caps how large a file we'll attempt to diff line-by-line.
+// Beyond this we fall back to a size/hash summary to keep the UI responsive.
+const diffMaxFileSize = 5 * 1024 * 1024 // 5 MiB
+
+// diffSniffLen is how many leading bytes we inspect to decide if a file is binary.
+const diffSniffLen = 8192
+
+type diffOpType int
+
+const (
+	diffEqual diffOpType = iota
+	diffDelete
+	diffInsert
+)
+
+type diffOp struct {
+	Type diffOpType
+	Line string
+}
+
+// getFileDiff returns a human-readable unified diff between file1 (the
+// existing destination file) and file2 (the newly received file), for
+// display in an OverwritePrompt. Binary files and files above
+// diffMaxFileSize are summarized instead of diffed line-by-line. Reads go
+// through fsys so this can be exercised against an in-memory filesystem in
+// tests.
+func getFileDiff(fsys FS, file1, file2 string) (string, error) {
+	info1, err := fsys.Stat(file1)
+	if err != nil {
+		return "", err
+	}
+	info2, err := fsys.Stat(file2)
+	if err != nil {
+		return "", err
+	}
+
+	if info1.Size() > diffMaxFileSize || info2.Size() > diffMaxFileSize {
+		return fmt.Sprintf("Files too large to diff (%d bytes vs %d bytes).", info1.Size(), info2.Size()), nil
+	}
+
+	f1, err := readAll(fsys, file1)
+	if err != nil {
+		return "", err
+	}
+	f2, err := readAll(fsys, file2)
+	if err != nil {
+		return "", err
+	}
+
+	if bytes.Equal(f1, f2) {
+		return "Files are identical.", nil
+	}
+
+	if isBinary(f1) || isBinary(f2) {
+		return fmt.Sprintf("Binary files a/%s and b/%s differ", filepath.Base(file1), filepath.Base(file2)), nil
+	}
+
+	return unifiedDiff(filepath.Base(file1), filepath.Base(file2), string(f1), string(f2)), nil
+}
+
+// readAll reads the full contents of name through fsys.
+func readAll(fsys FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// isBinary reports whether data looks like binary content, using the
+// common heuristic of a NUL byte appearing in the first diffSniffLen bytes.
+func isBinary(data []byte) bool {
+	if len(data) > diffSniffLen {
+		data = data[:diffSniffLen]
+	}
+	return bytes.IndexByte(data, 0) != -1
+}
+
+// unifiedDiff renders a unified diff between textA (old) and textB (new)
+// using Myers' shortest-edit-script algorithm, grouped into hunks with
+// three lines of surrounding context.
+func unifiedDiff(nameA, nameB, textA, textB string) string {
+	linesA := splitLines(textA)
+	linesB := splitLines(textB)
+
+	ops := myersDiff(linesA, linesB)
+	hunks := formatHunks(ops, 3)
+	if len(hunks) == 0 {
+		return "Files are identical."
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n", nameA)
+	fmt.Fprintf(&b, "+++ b/%s\n", nameB)
+	for _, h := range hunks {
+		b.WriteString(h)
+	}
+	return b.String()
+}
+
+// splitLines splits text into lines, dropping the trailing empty element
+// produced when text ends in a newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// myersDiff computes the shortest edit script transforming a into b using
+// Myers' O(ND) algorithm: for each edit distance D it walks diagonals
+// k = -D..D maintaining the furthest-reaching x for each, snaking forward
+// over matching lines, until the script reaching (len(a), len(b)) is found.
+// The result is returned as an ordered sequence of equal/delete/insert ops.
+func myersDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	offset := max
+	size := 2*max + 1
+	v := make([]int, size)
+	trace := make([][]int, 0, max+1)
+
+	found := false
+	dFound := 0
+
+loop:
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, size)
+		copy(snapshot, v)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				trace = append(trace, snapshot)
+				dFound = d
+				found = true
+				break loop
+			}
+		}
+		trace = append(trace, snapshot)
+	}
+	if !found {
+		dFound = len(trace) - 1
+	}
+
+	// Back-trace through the recorded V-arrays to recover the edit script.
+	x, y := n, m
+	var ops []diffOp
+	for d := dFound; d > 0; d-- {
+		snapshot := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && snapshot[offset+k-1] < snapshot[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := snapshot[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{Type: diffEqual, Line: a[x]})
+		}
+
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{Type: diffInsert, Line: b[y]})
+		} else {
+			x--
+			ops = append(ops, diffOp{Type: diffDelete, Line: a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, diffOp{Type: diffEqual, Line: a[x]})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// annotatedOp pairs a diffOp with its 1-based line number on each side.
+type annotatedOp struct {
+	diffOp
+	aLine int
+	bLine int
+}
+
+// formatHunks groups an edit script into unified-diff hunks, each
+// surrounded by up to `context` lines of unchanged content, merging hunks
+// whose context windows overlap.
+func formatHunks(ops []diffOp, context int) []string {
+	annotated := make([]annotatedOp, 0, len(ops))
+	aLine, bLine := 1, 1
+	for _, op := range ops {
+		annotated = append(annotated, annotatedOp{diffOp: op, aLine: aLine, bLine: bLine})
+		switch op.Type {
+		case diffEqual:
+			aLine++
+			bLine++
+		case diffDelete:
+			aLine++
+		case diffInsert:
+			bLine++
+		}
+	}
+
+	var ranges [][2]int
+	i := 0
+	for i < len(annotated) {
+		if annotated[i].Type == diffEqual {
+			i++
+			continue
+		}
+		start := i
+		for i < len(annotated) && annotated[i].Type != diffEqual {
+			i++
+		}
+		ranges = append(ranges, [2]int{start, i})
+	}
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	var windows [][2]int
+	for _, r := range ranges {
+		s := r[0] - context
+		if s < 0 {
+			s = 0
+		}
+		e := r[1] + context
+		if e > len(annotated) {
+			e = len(annotated)
+		}
+		if len(windows) > 0 && s <= windows[len(windows)-1][1] {
+			windows[len(windows)-1][1] = e
+		} else {
+			windows = append(windows, [2]int{s, e})
+		}
+	}
+
+	hunks := make([]string, 0, len(windows))
+	for _, w := range windows {
+		hunks = append(hunks, formatHunk(annotated[w[0]:w[1]]))
+	}
+	return hunks
+}
+
+// formatHunk renders a single hunk (header + context/+/- lines).
+func formatHunk(lines []annotatedOp) string {
+	aStart, bStart := lines[0].aLine, lines[0].bLine
+	var aCount, bCount int
+	var body strings.Builder
+	for _, l := range lines {
+		switch l.Type {
+		case diffEqual:
+			aCount++
+			bCount++
+			body.WriteString(" " + l.Line + "\n")
+		case diffDelete:
+			aCount++
+			body.WriteString("-" + l.Line + "\n")
+		case diffInsert:
+			bCount++
+			body.WriteString("+" + l.Line + "\n")
+		}
+	}
+
+	var hunk strings.Builder
+	fmt.Fprintf(&hunk, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+	hunk.WriteString(body.String())
+	return hunk.String()
+}
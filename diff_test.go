@@ -0,0 +1,183 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMyersDiff tests the edit-script computation for a few small cases
+func TestMyersDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []string
+		b    []string
+	}{
+		{"identical", []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		{"append", []string{"a", "b"}, []string{"a", "b", "c"}},
+		{"prepend", []string{"b", "c"}, []string{"a", "b", "c"}},
+		{"replace middle", []string{"a", "b", "c"}, []string{"a", "x", "c"}},
+		{"empty a", []string{}, []string{"a", "b"}},
+		{"empty b", []string{"a", "b"}, []string{}},
+		{"both empty", []string{}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops := myersDiff(tt.a, tt.b)
+
+			// Replaying the ops against a should reproduce b.
+			var got []string
+			ai := 0
+			for _, op := range ops {
+				switch op.Type {
+				case diffEqual:
+					got = append(got, op.Line)
+					ai++
+				case diffDelete:
+					ai++
+				case diffInsert:
+					got = append(got, op.Line)
+				}
+			}
+			_ = ai
+
+			if strings.Join(got, ",") != strings.Join(tt.b, ",") {
+				t.Errorf("replaying ops gave %v, want %v", got, tt.b)
+			}
+		})
+	}
+}
+
+// TestUnifiedDiff tests hunk formatting for a simple change
+func TestUnifiedDiff(t *testing.T) {
+	old := "line1\nline2\nline3\nline4\nline5\n"
+	new := "line1\nline2\nCHANGED\nline4\nline5\n"
+
+	diff := unifiedDiff("old.txt", "new.txt", old, new)
+
+	if !strings.Contains(diff, "--- a/old.txt") {
+		t.Error("missing old file header")
+	}
+	if !strings.Contains(diff, "+++ b/new.txt") {
+		t.Error("missing new file header")
+	}
+	if !strings.Contains(diff, "@@ ") {
+		t.Error("missing hunk header")
+	}
+	if !strings.Contains(diff, "-line3") {
+		t.Error("missing deletion line")
+	}
+	if !strings.Contains(diff, "+CHANGED") {
+		t.Error("missing insertion line")
+	}
+	if !strings.Contains(diff, " line2") {
+		t.Error("missing context line")
+	}
+}
+
+// TestIsBinary tests the NUL-byte sniffing heuristic
+func TestIsBinary(t *testing.T) {
+	if isBinary([]byte("hello world")) {
+		t.Error("plain text should not be detected as binary")
+	}
+	if !isBinary([]byte("hello\x00world")) {
+		t.Error("content with a NUL byte should be detected as binary")
+	}
+}
+
+// TestGetFileDiff tests the getFileDiff function
+func TestGetFileDiff(t *testing.T) {
+	tempDir := t.TempDir()
+
+	file1 := filepath.Join(tempDir, "file1.txt")
+	file2 := filepath.Join(tempDir, "file2.txt")
+
+	content1 := "Hello, World!"
+	content2 := "Hello, Universe!"
+
+	if err := os.WriteFile(file1, []byte(content1), 0644); err != nil {
+		t.Fatalf("failed to create file1: %v", err)
+	}
+	if err := os.WriteFile(file2, []byte(content2), 0644); err != nil {
+		t.Fatalf("failed to create file2: %v", err)
+	}
+
+	diff, err := getFileDiff(osFS{}, file1, file2)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if diff == "" {
+		t.Error("diff should not be empty for different files")
+	}
+	if !strings.Contains(diff, "--- a/file1.txt") {
+		t.Error("diff should contain file1 header")
+	}
+	if !strings.Contains(diff, "+++ b/file2.txt") {
+		t.Error("diff should contain file2 header")
+	}
+
+	diff2, err := getFileDiff(osFS{}, file1, file1)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expectedMsg := "Files are identical."
+	if diff2 != expectedMsg {
+		t.Errorf("expected '%s', got '%s'", expectedMsg, diff2)
+	}
+
+	if _, err := getFileDiff(osFS{}, "/nonexistent/file.txt", file1); err == nil {
+		t.Error("expected error for non-existent first file")
+	}
+	if _, err := getFileDiff(osFS{}, file1, "/nonexistent/file.txt"); err == nil {
+		t.Error("expected error for non-existent second file")
+	}
+}
+
+// TestGetFileDiff_Binary tests that binary files short-circuit to a summary
+func TestGetFileDiff_Binary(t *testing.T) {
+	tempDir := t.TempDir()
+
+	file1 := filepath.Join(tempDir, "binary1.bin")
+	file2 := filepath.Join(tempDir, "binary2.bin")
+
+	if err := os.WriteFile(file1, []byte{0x00, 0x01, 0x02}, 0644); err != nil {
+		t.Fatalf("failed to create binary1: %v", err)
+	}
+	if err := os.WriteFile(file2, []byte{0x00, 0x01, 0x03}, 0644); err != nil {
+		t.Fatalf("failed to create binary2: %v", err)
+	}
+
+	diff, err := getFileDiff(osFS{}, file1, file2)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !strings.Contains(diff, "Binary files") {
+		t.Errorf("expected binary summary, got %q", diff)
+	}
+}
+
+// TestGetFileDiff_TooLarge tests the size cap fallback
+func TestGetFileDiff_TooLarge(t *testing.T) {
+	tempDir := t.TempDir()
+
+	file1 := filepath.Join(tempDir, "big1.txt")
+	file2 := filepath.Join(tempDir, "big2.txt")
+
+	big := strings.Repeat("a", diffMaxFileSize+1)
+	if err := os.WriteFile(file1, []byte(big), 0644); err != nil {
+		t.Fatalf("failed to create big1: %v", err)
+	}
+	if err := os.WriteFile(file2, []byte("small"), 0644); err != nil {
+		t.Fatalf("failed to create big2: %v", err)
+	}
+
+	diff, err := getFileDiff(osFS{}, file1, file2)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !strings.Contains(diff, "too large") {
+		t.Errorf("expected too-large summary, got %q", diff)
+	}
+}
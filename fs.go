@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FS abstracts the filesystem operations App needs, modeled on
+// spf13/afero, so the receive path (tempdir creation, moving files out of
+// it, overwrite diffing) can run against an in-memory filesystem in tests
+// instead of touching the real disk.
+type FS interface {
+	Stat(name string) (os.FileInfo, error)
+	Open(name string) (io.ReadCloser, error)
+	Create(name string) (io.WriteCloser, error)
+	MkdirAll(path string, perm os.FileMode) error
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	RemoveAll(path string) error
+	Walk(root string, walkFn filepath.WalkFunc) error
+	TempDir(dir, pattern string) (string, error)
+	Chdir(dir string) error
+	Getwd() (string, error)
+}
+
+// osFS is the production FS, delegating straight to the os and
+// path/filepath packages.
+type osFS struct{}
+
+func (osFS) Stat(name string) (os.FileInfo, error)      { return os.Stat(name) }
+func (osFS) Open(name string) (io.ReadCloser, error)    { return os.Open(name) }
+func (osFS) Create(name string) (io.WriteCloser, error) { return os.Create(name) }
+func (osFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+func (osFS) Rename(oldpath, newpath string) error             { return os.Rename(oldpath, newpath) }
+func (osFS) Remove(name string) error                         { return os.Remove(name) }
+func (osFS) RemoveAll(path string) error                      { return os.RemoveAll(path) }
+func (osFS) Walk(root string, walkFn filepath.WalkFunc) error { return filepath.Walk(root, walkFn) }
+func (osFS) TempDir(dir, pattern string) (string, error)      { return os.MkdirTemp(dir, pattern) }
+func (osFS) Chdir(dir string) error                           { return os.Chdir(dir) }
+func (osFS) Getwd() (string, error)                           { return os.Getwd() }
+
+// memFileInfo is an in-memory os.FileInfo for memFS entries.
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	isDir   bool
+}
+
+func (fi *memFileInfo) Name() string       { return fi.name }
+func (fi *memFileInfo) Size() int64        { return fi.size }
+func (fi *memFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *memFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *memFileInfo) IsDir() bool        { return fi.isDir }
+func (fi *memFileInfo) Sys() interface{}   { return nil }
+
+// memFS is an in-memory FS for hermetic tests of the receive path, keyed
+// by cleaned absolute paths.
+type memFS struct {
+	mu          sync.Mutex
+	cwd         string
+	files       map[string][]byte
+	infos       map[string]*memFileInfo
+	tempCounter int
+}
+
+// newMemFS returns an empty in-memory filesystem rooted at "/".
+func newMemFS() *memFS {
+	return &memFS{
+		cwd:   "/",
+		files: make(map[string][]byte),
+		infos: map[string]*memFileInfo{
+			"/": {name: "/", isDir: true, mode: os.ModeDir | 0o755, modTime: time.Unix(0, 0)},
+		},
+	}
+}
+
+func (m *memFS) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Clean(filepath.Join(m.cwd, path))
+}
+
+// writeFile is a test helper that seeds a file (and its parent
+// directories) directly, without going through Create/Write/Close.
+func (m *memFS) writeFile(path string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	abs := m.resolve(path)
+	m.mkdirAllLocked(filepath.Dir(abs))
+	m.files[abs] = data
+	m.infos[abs] = &memFileInfo{name: filepath.Base(abs), size: int64(len(data)), modTime: time.Now()}
+}
+
+func (m *memFS) mkdirAllLocked(path string) {
+	path = filepath.Clean(path)
+	if path == "." || path == "/" {
+		if _, ok := m.infos["/"]; !ok {
+			m.infos["/"] = &memFileInfo{name: "/", isDir: true, mode: os.ModeDir | 0o755}
+		}
+		return
+	}
+	if info, ok := m.infos[path]; ok && info.isDir {
+		return
+	}
+	m.mkdirAllLocked(filepath.Dir(path))
+	m.infos[path] = &memFileInfo{name: filepath.Base(path), isDir: true, mode: os.ModeDir | 0o755, modTime: time.Now()}
+}
+
+func notExist(op, name string) error {
+	return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+}
+
+func (m *memFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	abs := m.resolve(name)
+	info, ok := m.infos[abs]
+	if !ok {
+		return nil, notExist("stat", name)
+	}
+	return info, nil
+}
+
+func (m *memFS) Open(name string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	abs := m.resolve(name)
+	data, ok := m.files[abs]
+	if !ok {
+		return nil, notExist("open", name)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// memWriteCloser buffers writes and commits them to the owning memFS on
+// Close, mirroring the write-then-rename pattern croc and the receive
+// path rely on.
+type memWriteCloser struct {
+	fs   *memFS
+	path string
+	buf  bytes.Buffer
+}
+
+func (w *memWriteCloser) Write(p []byte) (int, error) { return w.buf.Write(p) }
+
+func (w *memWriteCloser) Close() error {
+	w.fs.writeFile(w.path, w.buf.Bytes())
+	return nil
+}
+
+func (m *memFS) Create(name string) (io.WriteCloser, error) {
+	return &memWriteCloser{fs: m, path: name}, nil
+}
+
+func (m *memFS) MkdirAll(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.mkdirAllLocked(m.resolve(path))
+	return nil
+}
+
+func (m *memFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldAbs := m.resolve(oldpath)
+	newAbs := m.resolve(newpath)
+
+	if _, ok := m.infos[oldAbs]; !ok {
+		return notExist("rename", oldpath)
+	}
+
+	m.mkdirAllLocked(filepath.Dir(newAbs))
+
+	prefix := oldAbs + string(filepath.Separator)
+	renamed := false
+	for path := range m.infos {
+		if path == oldAbs || strings.HasPrefix(path, prefix) {
+			target := newAbs + strings.TrimPrefix(path, oldAbs)
+			m.infos[target] = m.infos[path]
+			m.infos[target].name = filepath.Base(target)
+			delete(m.infos, path)
+			if data, ok := m.files[path]; ok {
+				m.files[target] = data
+				delete(m.files, path)
+			}
+			renamed = true
+		}
+	}
+	if !renamed {
+		return notExist("rename", oldpath)
+	}
+	return nil
+}
+
+func (m *memFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	abs := m.resolve(name)
+	if _, ok := m.infos[abs]; !ok {
+		return notExist("remove", name)
+	}
+	for path := range m.infos {
+		if path != abs && strings.HasPrefix(path, abs+string(filepath.Separator)) {
+			return errors.Errorf("directory not empty: %s", name)
+		}
+	}
+	delete(m.infos, abs)
+	delete(m.files, abs)
+	return nil
+}
+
+func (m *memFS) RemoveAll(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	abs := m.resolve(path)
+	prefix := abs + string(filepath.Separator)
+	for p := range m.infos {
+		if p == abs || strings.HasPrefix(p, prefix) {
+			delete(m.infos, p)
+			delete(m.files, p)
+		}
+	}
+	return nil
+}
+
+func (m *memFS) Walk(root string, walkFn filepath.WalkFunc) error {
+	m.mu.Lock()
+	abs := m.resolve(root)
+	rootInfo, ok := m.infos[abs]
+	if !ok {
+		m.mu.Unlock()
+		return walkFn(root, nil, notExist("walk", root))
+	}
+
+	prefix := abs + string(filepath.Separator)
+	paths := []string{abs}
+	for p := range m.infos {
+		if p != abs && strings.HasPrefix(p, prefix) {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	infos := make(map[string]*memFileInfo, len(paths))
+	for _, p := range paths {
+		infos[p] = m.infos[p]
+	}
+	m.mu.Unlock()
+
+	if err := walkFn(root, rootInfo, nil); err != nil {
+		return err
+	}
+	for _, p := range paths[1:] {
+		if err := walkFn(p, infos[p], nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memFS) TempDir(dir, pattern string) (string, error) {
+	m.mu.Lock()
+	m.tempCounter++
+	n := m.tempCounter
+	m.mu.Unlock()
+
+	var name string
+	if strings.Contains(pattern, "*") {
+		name = strings.Replace(pattern, "*", strconv.Itoa(n), 1)
+	} else {
+		name = pattern + strconv.Itoa(n)
+	}
+	if dir == "" {
+		dir = "/tmp"
+	}
+
+	path := filepath.Join(dir, name)
+
+	m.mu.Lock()
+	m.mkdirAllLocked(m.resolve(path))
+	m.mu.Unlock()
+
+	return path, nil
+}
+
+func (m *memFS) Chdir(dir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	abs := m.resolve(dir)
+	info, ok := m.infos[abs]
+	if !ok || !info.isDir {
+		return notExist("chdir", dir)
+	}
+	m.cwd = abs
+	return nil
+}
+
+func (m *memFS) Getwd() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.cwd, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// TestMemFS_BasicOps exercises the in-memory FS in isolation, independent
+// of listFiles/getFileDiff, so a bug in one isn't masked by the other.
+func TestMemFS_BasicOps(t *testing.T) {
+	fs := newMemFS()
+
+	if err := fs.MkdirAll("/dest", 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	w, err := fs.Create("/dest/file.txt")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := w.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	info, err := fs.Stat("/dest/file.txt")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size() != 5 {
+		t.Errorf("expected size 5, got %d", info.Size())
+	}
+
+	if err := fs.Rename("/dest/file.txt", "/dest/renamed.txt"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if _, err := fs.Stat("/dest/file.txt"); err == nil {
+		t.Error("expected old path to be gone after rename")
+	}
+	if _, err := fs.Stat("/dest/renamed.txt"); err != nil {
+		t.Errorf("expected renamed file to exist: %v", err)
+	}
+
+	if err := fs.Chdir("/dest"); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+	cwd, err := fs.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	if cwd != "/dest" {
+		t.Errorf("expected cwd /dest, got %s", cwd)
+	}
+
+	if err := fs.RemoveAll("/dest"); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	if _, err := fs.Stat("/dest/renamed.txt"); err == nil {
+		t.Error("expected file to be gone after RemoveAll")
+	}
+}
+
+// TestListFiles_MemFS runs TestListFiles_EdgeCases' nested-directory case
+// against memFS instead of the real disk.
+func TestListFiles_MemFS(t *testing.T) {
+	fs := newMemFS()
+	fs.writeFile("/src/file1.txt", []byte("a"))
+	fs.writeFile("/src/level1/file2.txt", []byte("bb"))
+	fs.writeFile("/src/level1/level2/file3.txt", []byte("ccc"))
+
+	files, err := listFiles(fs, "/src")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(files))
+	}
+	if info, ok := files["/src/level1/level2/file3.txt"]; !ok {
+		t.Error("expected nested file3.txt to be found")
+	} else if info.Size() != 3 {
+		t.Errorf("expected size 3, got %d", info.Size())
+	}
+}
+
+// TestGetFileDiff_MemFS exercises getFileDiff against an in-memory
+// filesystem with no real files on disk.
+func TestGetFileDiff_MemFS(t *testing.T) {
+	fs := newMemFS()
+	fs.writeFile("/existing.txt", []byte("line1\nline2\n"))
+	fs.writeFile("/incoming.txt", []byte("line1\nline2 changed\n"))
+
+	diff, err := getFileDiff(fs, "/existing.txt", "/incoming.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff == "" {
+		t.Error("expected non-empty diff for differing files")
+	}
+}
+
+// TestApp_startup_DefaultFS verifies startup wires up the production FS
+// when none was injected, and leaves a pre-injected FS (as a test would
+// set for hermetic runs) untouched.
+func TestApp_startup_DefaultFS(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+	if _, ok := app.fs.(osFS); !ok {
+		t.Errorf("expected startup to default fs to osFS, got %T", app.fs)
+	}
+
+	mem := newMemFS()
+	app2 := &App{fs: mem}
+	app2.startup(context.Background())
+	if app2.fs != mem {
+		t.Error("expected startup to leave a pre-injected fs untouched")
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+	"github.com/schollz/croc/v10/src/utils"
+)
+
+// supportedHashAlgorithms mirrors the algorithms croc's utils.HashFile
+// understands; SetHashAlgorithm rejects anything outside this set.
+var supportedHashAlgorithms = map[string]bool{
+	"xxhash":  true,
+	"sha256":  true,
+	"md5":     true,
+	"highway": true,
+	"imohash": true,
+}
+
+// expectedHash looks up the sender-reported hash for a received file by
+// name, from the Files a Transport's final Progress update reported.
+func expectedHash(files []TransferredFile, name string) ([]byte, bool) {
+	for _, f := range files {
+		if f.Name == name {
+			return f.Hash, true
+		}
+	}
+	return nil, false
+}
+
+// verifyFileHash recomputes the hash of the file at path using algorithm
+// and compares it against expected, returning an error describing the
+// mismatch if verification fails.
+func verifyFileHash(path, algorithm string, expected []byte) error {
+	actual, err := utils.HashFile(path, algorithm)
+	if err != nil {
+		return errors.Wrapf(err, "failed to hash received file %s", path)
+	}
+	if !bytes.Equal(actual, expected) {
+		return errors.Errorf("hash mismatch for %s: expected %x, got %x", path, expected, actual)
+	}
+	return nil
+}
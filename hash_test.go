@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/schollz/croc/v10/src/utils"
+)
+
+// TestExpectedHash tests looking up a sender-reported hash by file name
+func TestExpectedHash(t *testing.T) {
+	files := []TransferredFile{
+		{Name: "a.txt", Hash: []byte{1, 2, 3}},
+		{Name: "b.txt", Hash: []byte{4, 5, 6}},
+	}
+
+	hash, ok := expectedHash(files, "b.txt")
+	if !ok {
+		t.Fatal("expected to find hash for b.txt")
+	}
+	if string(hash) != string([]byte{4, 5, 6}) {
+		t.Errorf("unexpected hash: %v", hash)
+	}
+
+	if _, ok := expectedHash(files, "missing.txt"); ok {
+		t.Error("expected no hash for missing.txt")
+	}
+}
+
+// TestVerifyFileHash tests that matching and mismatching hashes are detected
+func TestVerifyFileHash(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "data.bin")
+
+	if err := os.WriteFile(filePath, []byte("hello world"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	goodHash, err := utils.HashFile(filePath, "xxhash")
+	if err != nil {
+		t.Fatalf("failed to hash test file: %v", err)
+	}
+
+	if err := verifyFileHash(filePath, "xxhash", goodHash); err != nil {
+		t.Errorf("expected verification to succeed, got %v", err)
+	}
+
+	badHash := []byte{0xde, 0xad, 0xbe, 0xef}
+	if err := verifyFileHash(filePath, "xxhash", badHash); err == nil {
+		t.Error("expected verification to fail for mismatched hash")
+	}
+}
+
+// TestApp_SetHashAlgorithm tests the hash algorithm setter and its default
+func TestApp_SetHashAlgorithm(t *testing.T) {
+	app := &App{}
+
+	if got := app.getHashAlgorithm(); got != defaultHashAlgorithm {
+		t.Errorf("expected default algorithm %s, got %s", defaultHashAlgorithm, got)
+	}
+
+	if err := app.SetHashAlgorithm("sha256"); err != nil {
+		t.Errorf("unexpected error setting sha256: %v", err)
+	}
+	if got := app.getHashAlgorithm(); got != "sha256" {
+		t.Errorf("expected sha256, got %s", got)
+	}
+
+	if err := app.SetHashAlgorithm("not-a-real-algorithm"); err == nil {
+		t.Error("expected error for unsupported algorithm")
+	}
+	if got := app.getHashAlgorithm(); got != "sha256" {
+		t.Errorf("algorithm should be unchanged after rejected update, got %s", got)
+	}
+}
@@ -100,8 +100,51 @@ func TestReceiveFile_Integration(t *testing.T) {
 	if transfer.Code != code {
 		t.Errorf("transfer code mismatch: expected %s, got %s", code, transfer.Code)
 	}
-	
+
 	if transfer.Status != FileTransferStatusPreparing {
 		t.Errorf("transfer status should be preparing, got %s", transfer.Status)
 	}
+}
+
+// TestSendFiles_Integration tests SendFiles without waiting for goroutine
+// completion, the same way TestSendFile_Integration covers SendFile.
+func TestSendFiles_Integration(t *testing.T) {
+	// Skip if running in short mode
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	app := &App{emit: func(context.Context, string, ...interface{}) {}}
+	app.startup(context.Background())
+
+	tempDir := t.TempDir()
+	file1 := filepath.Join(tempDir, "one.txt")
+	file2 := filepath.Join(tempDir, "two.txt")
+
+	if err := os.WriteFile(file1, []byte("one"), 0644); err != nil {
+		t.Fatalf("failed to create %s: %v", file1, err)
+	}
+	if err := os.WriteFile(file2, []byte("two!!"), 0644); err != nil {
+		t.Fatalf("failed to create %s: %v", file2, err)
+	}
+
+	transfer, err := app.SendFiles([]string{file1, file2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if transfer.ID == "" {
+		t.Error("transfer ID should not be empty")
+	}
+	if len(transfer.Manifest) != 2 {
+		t.Fatalf("expected 2 manifest entries, got %d", len(transfer.Manifest))
+	}
+	if transfer.Size != 8 {
+		t.Errorf("expected total size 8, got %d", transfer.Size)
+	}
+
+	// Verify transfer was added
+	if app.Len() != 1 {
+		t.Errorf("expected 1 transfer, got %d", app.Len())
+	}
 }
\ No newline at end of file
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/schollz/croc/v10/src/utils"
+)
+
+// lanAnnouncePrefix marks a UDP packet as a lanTransport announcement, so
+// Accept can ignore unrelated broadcast traffic sharing the same port.
+const lanAnnouncePrefix = "KROKODYL-LAN"
+
+// lanDiscoveryPort is the UDP port lanTransport broadcasts announcements
+// to and listens for them on by default. Chosen arbitrarily, outside the
+// IANA ephemeral range.
+const lanDiscoveryPort = 53121
+
+// lanAnnounceInterval is how often Offer rebroadcasts its announcement
+// while waiting for a peer to connect.
+const lanAnnounceInterval = 500 * time.Millisecond
+
+// lanCopyBufferSize is the buffer size Offer/Accept stream file bytes
+// through over their TCP connection.
+const lanCopyBufferSize = 32 * 1024
+
+// lanTransport is a direct, relay-free Transport for peers on the same
+// local network: Offer announces a code over UDP broadcast - a lightweight
+// stand-in for full mDNS/RFC 6762 service discovery, since the repo has no
+// existing mDNS dependency to build one on - and streams the file over TCP
+// to whichever peer connects; Accept listens for an announcement matching
+// the code and connects to it. There's no relay involved at all, so this
+// only works between peers that can reach each other directly (e.g. the
+// same subnet).
+type lanTransport struct {
+	// broadcastAddr is where Offer sends UDP announcements. Normally the
+	// subnet broadcast address (e.g. "255.255.255.255:53121"); tests
+	// override it with a loopback address, since broadcast isn't available
+	// in a sandboxed network namespace.
+	broadcastAddr string
+
+	// listenPort is the UDP port Accept listens for announcements on. It
+	// must match the port broadcastAddr uses on the sending side.
+	listenPort int
+
+	// announceInterval overrides lanAnnounceInterval; zero means use the
+	// default. Tests use a shorter interval so they don't need to wait.
+	announceInterval time.Duration
+}
+
+// newLANTransport returns a lanTransport configured for real subnet
+// broadcast discovery on lanDiscoveryPort.
+func newLANTransport() *lanTransport {
+	return &lanTransport{
+		broadcastAddr: fmt.Sprintf("255.255.255.255:%d", lanDiscoveryPort),
+		listenPort:    lanDiscoveryPort,
+	}
+}
+
+func (t *lanTransport) Name() string { return "lan" }
+
+func (t *lanTransport) interval() time.Duration {
+	if t.announceInterval > 0 {
+		return t.announceInterval
+	}
+	return lanAnnounceInterval
+}
+
+func (t *lanTransport) Offer(ctx context.Context, file string) (string, <-chan Progress, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "failed to stat file: %s", file)
+	}
+
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to start LAN listener")
+	}
+
+	announceConn, err := net.Dial("udp", t.broadcastAddr)
+	if err != nil {
+		listener.Close()
+		return "", nil, errors.Wrapf(err, "failed to open discovery socket to %s", t.broadcastAddr)
+	}
+
+	_, portStr, _ := net.SplitHostPort(listener.Addr().String())
+	code := utils.GetRandomName()
+	announcement := []byte(fmt.Sprintf("%s|%s|%s", lanAnnouncePrefix, code, portStr))
+
+	progress := make(chan Progress)
+	go func() {
+		defer close(progress)
+		defer listener.Close()
+		defer announceConn.Close()
+
+		peerCh := make(chan net.Conn, 1)
+		go func() {
+			peer, err := listener.Accept()
+			if err == nil {
+				peerCh <- peer
+			}
+		}()
+
+		ticker := time.NewTicker(t.interval())
+		defer ticker.Stop()
+		announceConn.Write(announcement)
+
+		var peer net.Conn
+		for peer == nil {
+			select {
+			case <-ctx.Done():
+				progress <- Progress{Err: ctx.Err()}
+				return
+			case peer = <-peerCh:
+			case <-ticker.C:
+				announceConn.Write(announcement)
+			}
+		}
+		defer peer.Close()
+
+		sendOverLAN(peer, file, info, progress)
+	}()
+
+	return code, progress, nil
+}
+
+func (t *lanTransport) Accept(ctx context.Context, code, destinationPath string) (<-chan Progress, error) {
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{Port: t.listenPort})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to listen for LAN announcements on port %d", t.listenPort)
+	}
+
+	progress := make(chan Progress)
+	go func() {
+		defer close(progress)
+		defer udpConn.Close()
+
+		if deadline, ok := ctx.Deadline(); ok {
+			udpConn.SetReadDeadline(deadline)
+		}
+
+		peerHost, peerPort, err := waitForAnnouncement(udpConn, code)
+		if err != nil {
+			progress <- Progress{Err: err}
+			return
+		}
+
+		conn, err := net.Dial("tcp", net.JoinHostPort(peerHost, peerPort))
+		if err != nil {
+			progress <- Progress{Err: errors.Wrap(err, "failed to connect to LAN peer")}
+			return
+		}
+		defer conn.Close()
+
+		receiveOverLAN(conn, destinationPath, progress)
+	}()
+
+	return progress, nil
+}
+
+// waitForAnnouncement reads announcements from udpConn until one matches
+// code, returning the sender's host and the TCP port it announced.
+func waitForAnnouncement(udpConn *net.UDPConn, code string) (host, port string, err error) {
+	buf := make([]byte, 256)
+	for {
+		n, addr, err := udpConn.ReadFromUDP(buf)
+		if err != nil {
+			return "", "", errors.Wrap(err, "no matching LAN announcement received")
+		}
+
+		parts := strings.Split(string(buf[:n]), "|")
+		if len(parts) != 3 || parts[0] != lanAnnouncePrefix || parts[1] != code {
+			continue
+		}
+		return addr.IP.String(), parts[2], nil
+	}
+}
+
+// sendOverLAN writes a small header (name, then size, newline-delimited)
+// followed by path's raw bytes to conn, reporting Progress as it goes.
+func sendOverLAN(conn net.Conn, path string, info os.FileInfo, progress chan<- Progress) {
+	f, err := os.Open(path)
+	if err != nil {
+		progress <- Progress{Err: errors.Wrapf(err, "failed to open %s", path)}
+		return
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(conn, "%s\n%d\n", filepath.Base(path), info.Size()); err != nil {
+		progress <- Progress{Err: errors.Wrap(err, "failed to send LAN transfer header")}
+		return
+	}
+
+	var sent int64
+	buf := make([]byte, lanCopyBufferSize)
+	for {
+		n, readErr := f.Read(buf)
+		if n > 0 {
+			if _, err := conn.Write(buf[:n]); err != nil {
+				progress <- Progress{Err: errors.Wrap(err, "failed to send file over LAN")}
+				return
+			}
+			sent += int64(n)
+			progress <- Progress{BytesTransferred: sent, TotalBytes: info.Size()}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			progress <- Progress{Err: errors.Wrapf(readErr, "failed to read %s", path)}
+			return
+		}
+	}
+	progress <- Progress{BytesTransferred: sent, TotalBytes: info.Size(), Done: true}
+}
+
+// receiveOverLAN reads sendOverLAN's header from conn, then streams the
+// announced number of bytes into destinationPath, reporting Progress as it
+// goes.
+func receiveOverLAN(conn net.Conn, destinationPath string, progress chan<- Progress) {
+	reader := bufio.NewReader(conn)
+
+	name, err := reader.ReadString('\n')
+	if err != nil {
+		progress <- Progress{Err: errors.Wrap(err, "failed to read LAN transfer header")}
+		return
+	}
+	sizeLine, err := reader.ReadString('\n')
+	if err != nil {
+		progress <- Progress{Err: errors.Wrap(err, "failed to read LAN transfer header")}
+		return
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(sizeLine), 10, 64)
+	if err != nil {
+		progress <- Progress{Err: errors.Wrap(err, "failed to parse LAN transfer size")}
+		return
+	}
+
+	// name is sender-controlled (read off the wire), so it's sanitized down
+	// to a bare file name before joining: otherwise a sender announcing
+	// something like "../../../../tmp/evil" could write outside
+	// destinationPath entirely.
+	baseName := filepath.Base(strings.TrimSpace(name))
+	if baseName == "" || baseName == "." || baseName == ".." {
+		progress <- Progress{Err: errors.Errorf("rejected unsafe LAN transfer file name %q", name)}
+		return
+	}
+
+	destPath := filepath.Join(destinationPath, baseName)
+	out, err := os.Create(destPath)
+	if err != nil {
+		progress <- Progress{Err: errors.Wrapf(err, "failed to create %s", destPath)}
+		return
+	}
+	defer out.Close()
+
+	var received int64
+	buf := make([]byte, lanCopyBufferSize)
+	for received < size {
+		n, readErr := reader.Read(buf)
+		if n > 0 {
+			if _, err := out.Write(buf[:n]); err != nil {
+				progress <- Progress{Err: errors.Wrapf(err, "failed to write %s", destPath)}
+				return
+			}
+			received += int64(n)
+			progress <- Progress{BytesTransferred: received, TotalBytes: size}
+		}
+		if readErr != nil {
+			if readErr == io.EOF && received >= size {
+				break
+			}
+			progress <- Progress{Err: errors.Wrap(readErr, "failed to read file over LAN")}
+			return
+		}
+	}
+	progress <- Progress{BytesTransferred: received, TotalBytes: size, Done: true}
+}
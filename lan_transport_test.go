@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// freeUDPPort asks the OS for an unused UDP port, so lanTransport tests
+// don't collide with each other or a real lanTransport on the machine
+// running them.
+func freeUDPPort(t *testing.T) int {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		t.Fatalf("failed to find a free UDP port: %v", err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// TestLANTransport_OfferAccept_Loopback exercises lanTransport end to end
+// over loopback: discovery uses a unicast address to 127.0.0.1 instead of
+// a real subnet broadcast, since broadcast isn't available in a sandboxed
+// network namespace, but otherwise runs the real announce/connect/stream
+// path.
+func TestLANTransport_OfferAccept_Loopback(t *testing.T) {
+	port := freeUDPPort(t)
+	sender := &lanTransport{
+		broadcastAddr:    "127.0.0.1:" + strconv.Itoa(port),
+		listenPort:       port,
+		announceInterval: 20 * time.Millisecond,
+	}
+	receiver := &lanTransport{
+		broadcastAddr:    "127.0.0.1:" + strconv.Itoa(port),
+		listenPort:       port,
+		announceInterval: 20 * time.Millisecond,
+	}
+
+	srcDir := t.TempDir()
+	srcPath := filepath.Join(srcDir, "hello.txt")
+	want := []byte("hello over the LAN")
+	if err := os.WriteFile(srcPath, want, 0644); err != nil {
+		t.Fatalf("failed to create source file: %v", err)
+	}
+	destDir := t.TempDir()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	code, offerProgress, err := sender.Offer(ctx, srcPath)
+	if err != nil {
+		t.Fatalf("unexpected error from Offer: %v", err)
+	}
+
+	acceptProgress, err := receiver.Accept(ctx, code, destDir)
+	if err != nil {
+		t.Fatalf("unexpected error from Accept: %v", err)
+	}
+
+	var offerDone, acceptDone Progress
+	for p := range offerProgress {
+		offerDone = p
+	}
+	for p := range acceptProgress {
+		acceptDone = p
+	}
+
+	if offerDone.Err != nil || !offerDone.Done {
+		t.Fatalf("expected Offer to finish successfully, got %+v", offerDone)
+	}
+	if acceptDone.Err != nil || !acceptDone.Done {
+		t.Fatalf("expected Accept to finish successfully, got %+v", acceptDone)
+	}
+
+	got, err := os.ReadFile(filepath.Join(destDir, "hello.txt"))
+	if err != nil {
+		t.Fatalf("failed to read received file: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("expected received content %q, got %q", want, got)
+	}
+}
+
+// TestLANTransport_Accept_NoAnnouncement tests that Accept returns an
+// error (rather than hanging forever) when ctx expires without a matching
+// announcement arriving.
+func TestLANTransport_Accept_NoAnnouncement(t *testing.T) {
+	port := freeUDPPort(t)
+	receiver := &lanTransport{listenPort: port}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	progress, err := receiver.Accept(ctx, "nobody-is-offering-this-code", t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error starting Accept: %v", err)
+	}
+
+	final, ok := <-progress
+	if !ok {
+		t.Fatal("expected a final Progress before the channel closed")
+	}
+	if final.Err == nil {
+		t.Error("expected an error when no matching announcement arrives before the deadline")
+	}
+}
+
+// TestReceiveOverLAN_RejectsPathTraversal tests that a sender-announced
+// file name trying to escape destinationPath (e.g. via "../") is confined
+// to destinationPath (via filepath.Base) instead of being joined verbatim
+// and written outside it.
+func TestReceiveOverLAN_RejectsPathTraversal(t *testing.T) {
+	destDir := t.TempDir()
+	outsideDir := t.TempDir()
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		defer server.Close()
+		client.Write([]byte("../../../../" + filepath.Base(outsideDir) + "/evil.txt\n5\n"))
+		client.Write([]byte("oops!"))
+	}()
+
+	progress := make(chan Progress, 4)
+	receiveOverLAN(server, destDir, progress)
+	close(progress)
+
+	var final Progress
+	for p := range progress {
+		final = p
+	}
+	if final.Err != nil {
+		t.Fatalf("unexpected error: %v", final.Err)
+	}
+	if !final.Done {
+		t.Fatal("expected the sanitized transfer to complete")
+	}
+
+	if _, err := os.Stat(filepath.Join(outsideDir, "evil.txt")); !os.IsNotExist(err) {
+		t.Error("expected no file to be written outside destinationPath")
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "evil.txt")); err != nil {
+		t.Errorf("expected the file to land inside destinationPath instead, got %v", err)
+	}
+}
+
+// TestReceiveOverLAN_RejectsBareTraversalName tests that an announced name
+// that is just "." or ".." (rather than a path containing one) is rejected
+// outright, since there's no safe file name left after sanitizing it.
+func TestReceiveOverLAN_RejectsBareTraversalName(t *testing.T) {
+	destDir := t.TempDir()
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		defer server.Close()
+		client.Write([]byte("..\n5\n"))
+		client.Write([]byte("oops!"))
+	}()
+
+	progress := make(chan Progress, 4)
+	receiveOverLAN(server, destDir, progress)
+	close(progress)
+
+	var final Progress
+	for p := range progress {
+		final = p
+	}
+	if final.Err == nil {
+		t.Fatal("expected an error rejecting a bare \"..\" file name")
+	}
+}
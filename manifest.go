@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/schollz/croc/v10/src/croc"
+	"github.com/schollz/croc/v10/src/utils"
+)
+
+// ManifestEntry describes one file within a batch transfer. Batch sends
+// follow the "batch" request pattern git-lfs uses for its transfer API:
+// one request negotiates a single session covering many objects, instead
+// of SendFile's one-code-phrase-per-file model.
+type ManifestEntry struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Sha   string `json:"sha"`
+	Index int    `json:"index"`
+}
+
+// FileProgressEvent is the payload emitted on TransferEventFileProgress,
+// letting the frontend render per-file progress for a batch transfer
+// instead of only the transfer-wide total TransferStatsEvent reports.
+type FileProgressEvent struct {
+	TransferID string `json:"transferId"`
+	FileIndex  int    `json:"fileIndex"`
+	Progress   int    `json:"progress"`
+}
+
+// buildManifest hashes each file croc.GetFilesInfo resolved (after
+// expanding directories) using algorithm, in the same order croc.Client
+// will send them in, so Index lines up with FilesToTransferCurrentNum.
+func buildManifest(filesInfo []croc.FileInfo, algorithm string) ([]ManifestEntry, error) {
+	manifest := make([]ManifestEntry, len(filesInfo))
+	for i, f := range filesInfo {
+		fullPath := filepath.Clean(filepath.Join(f.FolderSource, f.Name))
+
+		sum, err := utils.HashFile(fullPath, algorithm)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to hash %s", fullPath)
+		}
+
+		manifest[i] = ManifestEntry{
+			Path:  f.Name,
+			Size:  f.Size,
+			Sha:   hex.EncodeToString(sum),
+			Index: i,
+		}
+	}
+	return manifest, nil
+}
+
+// resolveSendPaths expands paths (files or directories) the same way
+// croc.GetFilesInfo does, failing fast with a clear error if any of them
+// doesn't exist, rather than letting a typo surface as a cryptic internal
+// croc error later.
+func resolveSendPaths(paths []string) error {
+	if len(paths) == 0 {
+		return errors.New("no files given")
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(p); err != nil {
+			return errors.Wrapf(err, "failed to stat file: %s", p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/schollz/croc/v10/src/croc"
+	"github.com/schollz/croc/v10/src/utils"
+)
+
+// TestBuildManifest tests that buildManifest hashes each file in order and
+// carries the Index a receiver would use to match FilesToTransferCurrentNum.
+func TestBuildManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	file1 := filepath.Join(dir, "a.txt")
+	file2 := filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(file1, []byte("hello"), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", file1, err)
+	}
+	if err := os.WriteFile(file2, []byte("world!!"), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", file2, err)
+	}
+
+	filesInfo := []croc.FileInfo{
+		{Name: "a.txt", FolderSource: dir, Size: 5},
+		{Name: "b.txt", FolderSource: dir, Size: 7},
+	}
+
+	manifest, err := buildManifest(filesInfo, "xxhash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(manifest) != 2 {
+		t.Fatalf("expected 2 manifest entries, got %d", len(manifest))
+	}
+
+	for i, entry := range manifest {
+		if entry.Index != i {
+			t.Errorf("entry %d: expected Index %d, got %d", i, i, entry.Index)
+		}
+	}
+	if manifest[0].Path != "a.txt" || manifest[0].Size != 5 {
+		t.Errorf("unexpected entry 0: %+v", manifest[0])
+	}
+
+	expectedSum, err := utils.HashFile(file1, "xxhash")
+	if err != nil {
+		t.Fatalf("failed to hash %s: %v", file1, err)
+	}
+	if manifest[0].Sha != hex.EncodeToString(expectedSum) {
+		t.Errorf("expected sha %s, got %s", hex.EncodeToString(expectedSum), manifest[0].Sha)
+	}
+}
+
+// TestBuildManifest_MissingFile tests that a manifest entry pointing at a
+// file that doesn't exist on disk surfaces a wrapped error instead of
+// panicking.
+func TestBuildManifest_MissingFile(t *testing.T) {
+	filesInfo := []croc.FileInfo{
+		{Name: "missing.txt", FolderSource: t.TempDir(), Size: 0},
+	}
+
+	if _, err := buildManifest(filesInfo, "xxhash"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+// TestResolveSendPaths tests the fail-fast validation SendFiles runs
+// before handing paths to croc.GetFilesInfo.
+func TestResolveSendPaths(t *testing.T) {
+	if err := resolveSendPaths(nil); err == nil {
+		t.Error("expected an error for no paths")
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("hi"), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", file, err)
+	}
+
+	if err := resolveSendPaths([]string{file, dir}); err != nil {
+		t.Errorf("unexpected error for existing paths: %v", err)
+	}
+
+	if err := resolveSendPaths([]string{file, filepath.Join(dir, "nope.txt")}); err == nil {
+		t.Error("expected an error for a nonexistent path")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// OverwritePolicy controls how the receive loop handles a file that
+// collides with one already at the destination, set either globally on
+// the App (SetOverwritePolicy) or for a single transfer
+// (SetTransferOverwritePolicy, or implicitly via RespondToOverwrite's
+// applyToAll parameter). The receive loop consults the effective policy
+// before ever emitting TransferEventOverwrite.
+type OverwritePolicy string
+
+const (
+	// OverwritePolicyAsk prompts via TransferEventOverwrite and waits for
+	// RespondToOverwrite, the original per-file behavior.
+	OverwritePolicyAsk OverwritePolicy = "ask"
+
+	// OverwritePolicyAlwaysOverwrite replaces the existing file without
+	// prompting.
+	OverwritePolicyAlwaysOverwrite OverwritePolicy = "always-overwrite"
+
+	// OverwritePolicyAlwaysSkip keeps the existing file and discards the
+	// incoming one without prompting.
+	OverwritePolicyAlwaysSkip OverwritePolicy = "always-skip"
+
+	// OverwritePolicyRenameWithSuffix and OverwritePolicyKeepBoth both keep
+	// the existing file untouched and save the incoming one alongside it
+	// under a non-colliding "name (n).ext" name, without prompting. They're
+	// kept as two names for the same outcome because the frontend surfaces
+	// them as distinct choices ("rename the new file" vs "keep both
+	// files").
+	OverwritePolicyRenameWithSuffix OverwritePolicy = "rename-with-suffix"
+	OverwritePolicyKeepBoth         OverwritePolicy = "keep-both"
+)
+
+// validOverwritePolicies is consulted by SetOverwritePolicy and
+// SetTransferOverwritePolicy to reject typos before they're stored.
+var validOverwritePolicies = map[OverwritePolicy]bool{
+	OverwritePolicyAsk:              true,
+	OverwritePolicyAlwaysOverwrite:  true,
+	OverwritePolicyAlwaysSkip:       true,
+	OverwritePolicyRenameWithSuffix: true,
+	OverwritePolicyKeepBoth:         true,
+}
+
+// picksNewName reports whether policy resolves a collision by giving the
+// incoming file a new name instead of prompting or discarding it.
+func (p OverwritePolicy) picksNewName() bool {
+	return p == OverwritePolicyRenameWithSuffix || p == OverwritePolicyKeepBoth
+}
+
+// maxRenameAttempts bounds nonCollidingName's search so a pathological
+// destination directory (thousands of pre-existing "file (n).txt" names)
+// fails loudly instead of looping forever.
+const maxRenameAttempts = 10000
+
+// nonCollidingName returns a "name (n).ext" variant of name that doesn't
+// exist in dir yet, trying n = 1, 2, 3, ... Used by
+// OverwritePolicyRenameWithSuffix/KeepBoth to pick a destination without
+// prompting.
+func nonCollidingName(fsys FS, dir, name string) (string, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	for n := 1; n <= maxRenameAttempts; n++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, n, ext)
+		if _, err := fsys.Stat(filepath.Join(dir, candidate)); err != nil {
+			return candidate, nil
+		}
+	}
+	return "", errors.Errorf("could not find a non-colliding name for %s after %d attempts", name, maxRenameAttempts)
+}
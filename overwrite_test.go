@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// TestNonCollidingName tests that nonCollidingName skips names already
+// taken in dir and returns the first free "name (n).ext" variant.
+func TestNonCollidingName(t *testing.T) {
+	fsys := newMemFS()
+	fsys.writeFile("/dest/file (1).txt", []byte("a"))
+	fsys.writeFile("/dest/file (2).txt", []byte("b"))
+
+	name, err := nonCollidingName(fsys, "/dest", "file.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "file (3).txt" {
+		t.Errorf("expected 'file (3).txt', got %s", name)
+	}
+}
+
+// TestNonCollidingName_NoExtension tests that a name with no extension is
+// suffixed the same way, without leaving a trailing dot.
+func TestNonCollidingName_NoExtension(t *testing.T) {
+	fsys := newMemFS()
+	fsys.writeFile("/dest/README", []byte("a"))
+
+	name, err := nonCollidingName(fsys, "/dest", "README")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "README (1)" {
+		t.Errorf("expected 'README (1)', got %s", name)
+	}
+}
+
+// TestApp_OverwritePolicy tests SetOverwritePolicy/getOverwritePolicy
+// round-tripping and rejecting unknown policies.
+func TestApp_OverwritePolicy(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+
+	if policy := app.getOverwritePolicy(); policy != OverwritePolicyAsk {
+		t.Errorf("expected default policy %s, got %s", OverwritePolicyAsk, policy)
+	}
+
+	if err := app.SetOverwritePolicy(OverwritePolicyAlwaysOverwrite); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policy := app.getOverwritePolicy(); policy != OverwritePolicyAlwaysOverwrite {
+		t.Errorf("expected %s, got %s", OverwritePolicyAlwaysOverwrite, policy)
+	}
+
+	if err := app.SetOverwritePolicy("bogus"); err == nil {
+		t.Error("expected an error for an unsupported policy")
+	}
+}
+
+// TestApp_EffectiveOverwritePolicy tests that a per-transfer override takes
+// precedence over the App's global policy.
+func TestApp_EffectiveOverwritePolicy(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+
+	if err := app.SetOverwritePolicy(OverwritePolicyAlwaysSkip); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policy := app.effectiveOverwritePolicy("t1"); policy != OverwritePolicyAlwaysSkip {
+		t.Errorf("expected global policy %s, got %s", OverwritePolicyAlwaysSkip, policy)
+	}
+
+	if err := app.SetTransferOverwritePolicy("t1", OverwritePolicyRenameWithSuffix); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policy := app.effectiveOverwritePolicy("t1"); policy != OverwritePolicyRenameWithSuffix {
+		t.Errorf("expected override %s, got %s", OverwritePolicyRenameWithSuffix, policy)
+	}
+	if policy := app.effectiveOverwritePolicy("t2"); policy != OverwritePolicyAlwaysSkip {
+		t.Errorf("expected transfer t2 to still see the global policy, got %s", policy)
+	}
+}
+
+// TestApp_RespondToOverwrite_ApplyToAll tests that applyToAll installs a
+// per-transfer policy override matching the response.
+func TestApp_RespondToOverwrite_ApplyToAll(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+
+	ch := app.registry.RegisterOverwriteResponse("t1")
+	go app.RespondToOverwrite("t1", "yes", true)
+	<-ch
+
+	if policy := app.effectiveOverwritePolicy("t1"); policy != OverwritePolicyAlwaysOverwrite {
+		t.Errorf("expected %s after applyToAll yes, got %s", OverwritePolicyAlwaysOverwrite, policy)
+	}
+
+	ch = app.registry.RegisterOverwriteResponse("t2")
+	go app.RespondToOverwrite("t2", "no", true)
+	<-ch
+
+	if policy := app.effectiveOverwritePolicy("t2"); policy != OverwritePolicyAlwaysSkip {
+		t.Errorf("expected %s after applyToAll no, got %s", OverwritePolicyAlwaysSkip, policy)
+	}
+}
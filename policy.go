@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/schollz/croc/v10/src/croc"
+	"github.com/sirupsen/logrus"
+)
+
+// TransferPolicy bounds what SendFile/SendFiles will queue and what a
+// receive will accept onto disk: a maximum size per file, a cap on how many
+// bytes can be queued across all not-yet-finished transfers at once, an
+// allow/deny list of MIME types and extensions, and an optional per-relay
+// quota. A zero-value TransferPolicy (every limit 0, every list empty) means
+// unrestricted, so existing behavior is unchanged until SetTransferPolicy is
+// called. Modeled on dendrite's media repo config (MaxFileSizeBytes plus
+// content-type sniffing while an upload streams in), adapted to krokodyl's
+// relay-based sends and receives instead of HTTP uploads.
+type TransferPolicy struct {
+	// MaxFileSizeBytes rejects any single file larger than this. 0 means
+	// unlimited.
+	MaxFileSizeBytes int64 `json:"maxFileSizeBytes"`
+
+	// MaxTotalQueuedBytes rejects a send that would push the sum of every
+	// transfer not yet completed, errored, cancelled, or rejected past this
+	// total. 0 means unlimited.
+	MaxTotalQueuedBytes int64 `json:"maxTotalQueuedBytes"`
+
+	// AllowedMIMETypes/DeniedMIMETypes match against http.DetectContentType's
+	// sniffed result on receive, or mime.TypeByExtension on send (where no
+	// file content is available to sniff yet). An empty AllowedMIMETypes
+	// means every type is allowed unless it appears in DeniedMIMETypes;
+	// DeniedMIMETypes always wins over AllowedMIMETypes for a type in both.
+	AllowedMIMETypes []string `json:"allowedMimeTypes,omitempty"`
+	DeniedMIMETypes  []string `json:"deniedMimeTypes,omitempty"`
+
+	// AllowedExtensions/DeniedExtensions match filepath.Ext case-insensitively,
+	// dot included (e.g. ".zip"), with the same allow/deny precedence as the
+	// MIME lists.
+	AllowedExtensions []string `json:"allowedExtensions,omitempty"`
+	DeniedExtensions  []string `json:"deniedExtensions,omitempty"`
+
+	// PerPeerQuotaBytes caps total queued bytes per relay name, for an
+	// operator who shares one krokodyl instance across several relays and
+	// wants to bound any single one's usage independent of
+	// MaxTotalQueuedBytes. A relay absent from this map has no per-relay cap.
+	PerPeerQuotaBytes map[string]int64 `json:"perPeerQuotaBytes,omitempty"`
+}
+
+var (
+	// ErrFileTooLarge is returned when a file exceeds TransferPolicy's
+	// MaxFileSizeBytes, or when queuing it would exceed MaxTotalQueuedBytes
+	// or a relay's PerPeerQuotaBytes.
+	ErrFileTooLarge = errors.New("file exceeds the configured transfer policy size limit")
+
+	// ErrDisallowedType is returned when a file's extension, or (on receive)
+	// its sniffed content type, isn't permitted by TransferPolicy.
+	ErrDisallowedType = errors.New("file type is not permitted by the configured transfer policy")
+)
+
+// defaultTransferPolicy is unrestricted, so a fresh install behaves exactly
+// like krokodyl did before TransferPolicy existed.
+func defaultTransferPolicy() TransferPolicy {
+	return TransferPolicy{}
+}
+
+// policyConfigFile is the name of the JSON file persisted under the user's
+// config dir, alongside relay.go's relays.json and config.go's config.json.
+const policyConfigFile = "policy.json"
+
+// policyFilePath resolves the path SetTransferPolicy persists to and
+// startup loads from.
+func policyFilePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to resolve user config dir")
+	}
+	return filepath.Join(configDir, "krokodyl", policyConfigFile), nil
+}
+
+// loadPersistedPolicy reads the TransferPolicy persisted at path, falling
+// back to defaultTransferPolicy if the file doesn't exist yet.
+func loadPersistedPolicy(path string) (TransferPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return defaultTransferPolicy(), nil
+		}
+		return TransferPolicy{}, errors.Wrapf(err, "failed to read transfer policy: %s", path)
+	}
+
+	var policy TransferPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return TransferPolicy{}, errors.Wrapf(err, "failed to parse transfer policy: %s", path)
+	}
+	return policy, nil
+}
+
+// savePersistedPolicy writes policy as JSON to path, creating its parent
+// directory if needed.
+func savePersistedPolicy(path string, policy TransferPolicy) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrapf(err, "failed to create config dir for %s", path)
+	}
+
+	data, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal transfer policy")
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return errors.Wrapf(err, "failed to write transfer policy: %s", path)
+	}
+	return nil
+}
+
+// matchesAny reports whether value equals any entry in list, case-insensitively.
+func matchesAny(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSize enforces MaxFileSizeBytes against a single file's size.
+func (p TransferPolicy) checkSize(size int64) error {
+	if p.MaxFileSizeBytes > 0 && size > p.MaxFileSizeBytes {
+		return ErrFileTooLarge
+	}
+	return nil
+}
+
+// checkType enforces the extension and MIME allow/deny lists against name.
+// sniffedMIME is the result of http.DetectContentType on receive, or empty
+// on send, in which case mime.TypeByExtension(filepath.Ext(name)) is used
+// instead since there's no content yet to sniff.
+func (p TransferPolicy) checkType(name, sniffedMIME string) error {
+	ext := strings.ToLower(filepath.Ext(name))
+	if len(p.DeniedExtensions) > 0 && matchesAny(p.DeniedExtensions, ext) {
+		return ErrDisallowedType
+	}
+	if len(p.AllowedExtensions) > 0 && !matchesAny(p.AllowedExtensions, ext) {
+		return ErrDisallowedType
+	}
+
+	mimeType := sniffedMIME
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(ext)
+	}
+	if mimeType == "" {
+		return nil
+	}
+
+	if len(p.DeniedMIMETypes) > 0 && matchesAny(p.DeniedMIMETypes, mimeType) {
+		return ErrDisallowedType
+	}
+	if len(p.AllowedMIMETypes) > 0 && !matchesAny(p.AllowedMIMETypes, mimeType) {
+		return ErrDisallowedType
+	}
+	return nil
+}
+
+// GetTransferPolicy returns the TransferPolicy currently in effect.
+func (a *App) GetTransferPolicy() TransferPolicy {
+	a.policyMu.Lock()
+	defer a.policyMu.Unlock()
+
+	return a.policy
+}
+
+// SetTransferPolicy replaces the policy every subsequent SendFile/SendFiles
+// call and receive checks against, and persists it to policyFilePath so
+// it's still in effect after a restart.
+func (a *App) SetTransferPolicy(p TransferPolicy) error {
+	a.policyMu.Lock()
+	a.policy = p
+	path := a.policyPath
+	a.policyMu.Unlock()
+
+	if path == "" {
+		return nil
+	}
+	return savePersistedPolicy(path, p)
+}
+
+// queuedBytes sums the size of every transfer not yet in a terminal state,
+// for checkQueueAndQuota's MaxTotalQueuedBytes/PerPeerQuotaBytes checks.
+// perPeer is the same sum restricted to transfers that resolved to
+// relayName.
+func (a *App) queuedBytes(relayName string) (total, perPeer int64) {
+	for _, t := range a.registry.Snapshot() {
+		switch t.Status {
+		case FileTransferStatusCompleted, FileTransferStatusError, FileTransferStatusCancelled, FileTransferStatusRejected:
+			continue
+		}
+		total += t.Size
+		if relayName != "" && t.RelayName == relayName {
+			perPeer += t.Size
+		}
+	}
+	return total, perPeer
+}
+
+// checkQueueAndQuota enforces MaxTotalQueuedBytes and relayName's
+// PerPeerQuotaBytes against addedSize more bytes being queued, on top of
+// whatever's already queued.
+func (a *App) checkQueueAndQuota(addedSize int64, relayName string) error {
+	policy := a.GetTransferPolicy()
+
+	total, perPeer := a.queuedBytes(relayName)
+	if policy.MaxTotalQueuedBytes > 0 && total+addedSize > policy.MaxTotalQueuedBytes {
+		return ErrFileTooLarge
+	}
+	if quota, ok := policy.PerPeerQuotaBytes[relayName]; ok && quota > 0 && perPeer+addedSize > quota {
+		return ErrFileTooLarge
+	}
+	return nil
+}
+
+// checkSendPolicy enforces TransferPolicy's size and type limits against a
+// single file about to be queued for send, plus the queue-wide checks via
+// checkQueueAndQuota.
+func (a *App) checkSendPolicy(name string, size int64, relayName string) error {
+	policy := a.GetTransferPolicy()
+
+	if err := policy.checkSize(size); err != nil {
+		return err
+	}
+	if err := policy.checkType(name, ""); err != nil {
+		return err
+	}
+	return a.checkQueueAndQuota(size, relayName)
+}
+
+// checkSendFilesPolicy is checkSendPolicy for a SendFiles batch: every file
+// is checked individually against size/type, then the batch's total size is
+// checked against the queue-wide limits once.
+func (a *App) checkSendFilesPolicy(filesInfo []croc.FileInfo, totalSize int64, relayName string) error {
+	policy := a.GetTransferPolicy()
+
+	for _, f := range filesInfo {
+		if err := policy.checkSize(f.Size); err != nil {
+			return err
+		}
+		if err := policy.checkType(f.Name, ""); err != nil {
+			return err
+		}
+	}
+	return a.checkQueueAndQuota(totalSize, relayName)
+}
+
+// sniffContentTypeSampleSize is how many leading bytes checkReceivedFile
+// samples, matching the sample size http.DetectContentType itself documents.
+const sniffContentTypeSampleSize = 512
+
+// sniffContentType reads the first sniffContentTypeSampleSize bytes of the
+// file at path and returns http.DetectContentType's guess.
+func sniffContentType(fsys FS, path string) (string, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to open %s", path)
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffContentTypeSampleSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", errors.Wrapf(err, "failed to read %s", path)
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// checkReceivedFile enforces TransferPolicy against a file that's just
+// finished receiving into tempDir: its size, and its sniffed content type,
+// against the configured limits. On a violation it deletes the partial file
+// at sourcePath before returning the error, mirroring dendrite's media repo
+// aborting and cleaning up a rejected upload mid-stream.
+func (a *App) checkReceivedFile(sourcePath, name string, size int64) error {
+	policy := a.GetTransferPolicy()
+
+	violation := policy.checkSize(size)
+	if violation == nil {
+		sniffed, err := sniffContentType(a.fs, sourcePath)
+		if err != nil {
+			logrus.WithError(err).Warnf("could not sniff content type of %s, skipping type check", sourcePath)
+		} else {
+			violation = policy.checkType(name, sniffed)
+		}
+	}
+
+	if violation != nil {
+		if err := a.fs.Remove(sourcePath); err != nil {
+			logrus.WithError(err).Warnf("failed to delete partial file %s rejected by transfer policy", sourcePath)
+		}
+	}
+	return violation
+}
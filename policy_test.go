@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestTransferPolicy_CheckSize tests that checkSize only rejects once
+// MaxFileSizeBytes is set and exceeded.
+func TestTransferPolicy_CheckSize(t *testing.T) {
+	var unrestricted TransferPolicy
+	if err := unrestricted.checkSize(1 << 40); err != nil {
+		t.Errorf("expected no limit by default, got %v", err)
+	}
+
+	limited := TransferPolicy{MaxFileSizeBytes: 100}
+	if err := limited.checkSize(100); err != nil {
+		t.Errorf("expected exactly the limit to be allowed, got %v", err)
+	}
+	if err := limited.checkSize(101); err != ErrFileTooLarge {
+		t.Errorf("expected ErrFileTooLarge, got %v", err)
+	}
+}
+
+// TestTransferPolicy_CheckType tests extension/MIME allow and deny lists,
+// and that deny takes precedence over allow for the same value.
+func TestTransferPolicy_CheckType(t *testing.T) {
+	var unrestricted TransferPolicy
+	if err := unrestricted.checkType("archive.zip", ""); err != nil {
+		t.Errorf("expected no restriction by default, got %v", err)
+	}
+
+	denyExt := TransferPolicy{DeniedExtensions: []string{".exe"}}
+	if err := denyExt.checkType("payload.EXE", ""); err != ErrDisallowedType {
+		t.Errorf("expected ErrDisallowedType for a denied extension (case-insensitively), got %v", err)
+	}
+	if err := denyExt.checkType("notes.txt", ""); err != nil {
+		t.Errorf("expected .txt to remain allowed, got %v", err)
+	}
+
+	allowExt := TransferPolicy{AllowedExtensions: []string{".png", ".jpg"}}
+	if err := allowExt.checkType("photo.png", ""); err != nil {
+		t.Errorf("expected an allow-listed extension to pass, got %v", err)
+	}
+	if err := allowExt.checkType("script.sh", ""); err != ErrDisallowedType {
+		t.Errorf("expected ErrDisallowedType for an extension missing from the allow list, got %v", err)
+	}
+
+	denyMIME := TransferPolicy{DeniedMIMETypes: []string{"application/x-msdownload"}}
+	if err := denyMIME.checkType("partial.bin", "application/x-msdownload"); err != ErrDisallowedType {
+		t.Errorf("expected ErrDisallowedType for a denied sniffed MIME type, got %v", err)
+	}
+
+	allowAndDeny := TransferPolicy{AllowedExtensions: []string{".bin"}, DeniedExtensions: []string{".bin"}}
+	if err := allowAndDeny.checkType("payload.bin", ""); err != ErrDisallowedType {
+		t.Error("expected deny to take precedence over allow for the same extension")
+	}
+}
+
+// TestLoadPersistedPolicy_DefaultsWithoutFile tests that loading from a
+// non-existent path falls back to defaultTransferPolicy instead of erroring.
+func TestLoadPersistedPolicy_DefaultsWithoutFile(t *testing.T) {
+	policy, err := loadPersistedPolicy(filepath.Join(t.TempDir(), "policy.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(policy, defaultTransferPolicy()) {
+		t.Errorf("expected default policy, got %+v", policy)
+	}
+}
+
+// TestSavePersistedPolicy_RoundTrip tests that a saved policy reloads with
+// the same values.
+func TestSavePersistedPolicy_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	want := TransferPolicy{
+		MaxFileSizeBytes:    1024,
+		MaxTotalQueuedBytes: 4096,
+		DeniedExtensions:    []string{".exe"},
+		PerPeerQuotaBytes:   map[string]int64{"schollz": 2048},
+	}
+
+	if err := savePersistedPolicy(path, want); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+
+	got, err := loadPersistedPolicy(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading: %v", err)
+	}
+	if got.MaxFileSizeBytes != want.MaxFileSizeBytes || got.MaxTotalQueuedBytes != want.MaxTotalQueuedBytes {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if got.PerPeerQuotaBytes["schollz"] != 2048 {
+		t.Errorf("expected per-peer quota to round-trip, got %+v", got.PerPeerQuotaBytes)
+	}
+}
+
+// TestApp_GetSetTransferPolicy_Persists tests that SetTransferPolicy is
+// reflected by GetTransferPolicy and written to the App's policyPath.
+func TestApp_GetSetTransferPolicy_Persists(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+	app.policyPath = filepath.Join(t.TempDir(), "policy.json")
+
+	want := TransferPolicy{MaxFileSizeBytes: 512}
+	if err := app.SetTransferPolicy(want); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := app.GetTransferPolicy(); got.MaxFileSizeBytes != want.MaxFileSizeBytes {
+		t.Errorf("expected GetTransferPolicy to reflect the new policy, got %+v", got)
+	}
+
+	persisted, err := loadPersistedPolicy(app.policyPath)
+	if err != nil {
+		t.Fatalf("unexpected error loading persisted policy: %v", err)
+	}
+	if persisted.MaxFileSizeBytes != want.MaxFileSizeBytes {
+		t.Errorf("expected persisted policy to reflect the new policy, got %+v", persisted)
+	}
+}
+
+// TestApp_QueuedBytes_IgnoresTerminalTransfers tests that completed, errored,
+// cancelled, and rejected transfers don't count toward the queue total, but
+// everything else does, split out per relay for the per-peer quota.
+func TestApp_QueuedBytes_IgnoresTerminalTransfers(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+
+	app.registry.Add(FileTransfer{ID: "done", Size: 100, Status: FileTransferStatusCompleted})
+	app.registry.Add(FileTransfer{ID: "failed", Size: 100, Status: FileTransferStatusError})
+	app.registry.Add(FileTransfer{ID: "gone", Size: 100, Status: FileTransferStatusCancelled})
+	app.registry.Add(FileTransfer{ID: "blocked", Size: 100, Status: FileTransferStatusRejected})
+	app.registry.Add(FileTransfer{ID: "active", Size: 50, Status: FileTransferStatusSending, RelayName: "schollz"})
+	app.registry.Add(FileTransfer{ID: "waiting", Size: 25, Status: FileTransferStatusWaiting, RelayName: "school"})
+
+	total, perPeer := app.queuedBytes("schollz")
+	if total != 75 {
+		t.Errorf("expected total queued bytes 75, got %d", total)
+	}
+	if perPeer != 50 {
+		t.Errorf("expected 50 queued bytes for schollz, got %d", perPeer)
+	}
+}
+
+// TestApp_SendFile_RejectedByPolicy tests that SendFile rejects an
+// oversized file with ErrFileTooLarge and records the attempt as
+// FileTransferStatusRejected instead of starting a transfer.
+func TestApp_SendFile_RejectedByPolicy(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+	if err := app.SetTransferPolicy(TransferPolicy{MaxFileSizeBytes: 4}); err != nil {
+		t.Fatalf("unexpected error setting policy: %v", err)
+	}
+
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "big.txt")
+	if err := os.WriteFile(path, []byte("more than four bytes"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	if _, err := app.SendFile(path); err != ErrFileTooLarge {
+		t.Errorf("expected ErrFileTooLarge, got %v", err)
+	}
+
+	transfers := app.GetTransfers()
+	if len(transfers) != 1 {
+		t.Fatalf("expected the rejected attempt to still be recorded, got %d transfers", len(transfers))
+	}
+	if transfers[0].Status != FileTransferStatusRejected {
+		t.Errorf("expected status rejected, got %s", transfers[0].Status)
+	}
+}
+
+// TestApp_CheckReceivedFile_DeletesRejectedPartial tests that a partial file
+// whose extension is denied is removed from disk and reported as an error,
+// rather than handed off for hash verification.
+func TestApp_CheckReceivedFile_DeletesRejectedPartial(t *testing.T) {
+	fsys := newMemFS()
+	app := &App{fs: fsys}
+	app.startup(context.Background())
+	app.fs = fsys
+	if err := app.SetTransferPolicy(TransferPolicy{DeniedExtensions: []string{".exe"}}); err != nil {
+		t.Fatalf("unexpected error setting policy: %v", err)
+	}
+
+	fsys.writeFile("/tmp/krokodyl-1/payload.exe", []byte("MZ fake binary content"))
+
+	if err := app.checkReceivedFile("/tmp/krokodyl-1/payload.exe", "payload.exe", 21); err != ErrDisallowedType {
+		t.Errorf("expected ErrDisallowedType, got %v", err)
+	}
+
+	if _, err := fsys.Stat("/tmp/krokodyl-1/payload.exe"); err == nil {
+		t.Error("expected the rejected partial file to be deleted")
+	}
+}
+
+// TestApp_CheckReceivedFile_AllowsPermittedFile tests that a file passing
+// both the size and type checks is left alone.
+func TestApp_CheckReceivedFile_AllowsPermittedFile(t *testing.T) {
+	fsys := newMemFS()
+	app := &App{fs: fsys}
+	app.startup(context.Background())
+	app.fs = fsys
+
+	fsys.writeFile("/tmp/krokodyl-1/notes.txt", []byte("hello world"))
+
+	if err := app.checkReceivedFile("/tmp/krokodyl-1/notes.txt", "notes.txt", 11); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := fsys.Stat("/tmp/krokodyl-1/notes.txt"); err != nil {
+		t.Error("expected the permitted file to be left in place")
+	}
+}
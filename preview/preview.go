@@ -0,0 +1,124 @@
+// Package preview generates small thumbnail previews for files moving
+// through a krokodyl transfer, so the UI can show what's being sent or
+// received before the transfer finishes. This mirrors the dynamic
+// thumbnailing dendrite's mediaapi does for media uploads, but runs
+// entirely locally instead of against an HTTP media repo.
+//
+// Generator is implemented per-mimetype so future formats (a PDF
+// first-page render, a video first-frame grab via ffmpeg) can register
+// alongside ImageGenerator without changing callers.
+package preview
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/jpeg"
+
+	_ "image/gif"
+	_ "image/png"
+)
+
+// MaxDimension is the width and height a thumbnail is resized to fit
+// within, preserving aspect ratio.
+const MaxDimension = 256
+
+// Generator produces a thumbnail preview for file content of the MIME
+// types it accepts.
+type Generator interface {
+	// Accepts reports whether this Generator can handle mimeType.
+	Accepts(mimeType string) bool
+
+	// Generate decodes data and returns a data: URI containing a small
+	// thumbnail of it.
+	Generate(data []byte) (string, error)
+}
+
+// generators is every registered Generator, consulted in order by
+// GenerateDataURI. ImageGenerator covers every still-image format Go's
+// image package can decode out of the box; a video or PDF Generator would
+// be appended here.
+var generators = []Generator{ImageGenerator{}}
+
+// GenerateDataURI returns a thumbnail data URI for data, using whichever
+// registered Generator accepts mimeType. It returns ok=false, rather than
+// an error, if no Generator handles mimeType or decoding fails, since
+// "no preview available" is the common case for most transferred files.
+func GenerateDataURI(mimeType string, data []byte) (uri string, ok bool) {
+	for _, g := range generators {
+		if !g.Accepts(mimeType) {
+			continue
+		}
+		uri, err := g.Generate(data)
+		if err != nil {
+			return "", false
+		}
+		return uri, true
+	}
+	return "", false
+}
+
+// ImageGenerator decodes JPEG, PNG, and GIF (registered with Go's image
+// package via the blank imports above) and re-encodes a resized copy as a
+// JPEG data URI.
+type ImageGenerator struct{}
+
+func (ImageGenerator) Accepts(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	default:
+		return false
+	}
+}
+
+func (ImageGenerator) Generate(data []byte) (string, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resize(img, MaxDimension), &jpeg.Options{Quality: 75}); err != nil {
+		return "", err
+	}
+
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// resize nearest-neighbor scales img so its longer side is at most max,
+// preserving aspect ratio, or returns img unchanged if it already fits.
+// Nearest-neighbor keeps this dependency-free (no golang.org/x/image/draw)
+// for what's only ever a small, disposable thumbnail rather than the
+// transferred file itself.
+func resize(img image.Image, max int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= max && srcH <= max {
+		return img
+	}
+
+	scale := float64(max) / float64(srcW)
+	if hScale := float64(max) / float64(srcH); hScale < scale {
+		scale = hScale
+	}
+	dstW := maxInt(int(float64(srcW)*scale), 1)
+	dstH := maxInt(int(float64(srcH)*scale), 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		srcY := bounds.Min.Y + y*srcH/dstH
+		for x := 0; x < dstW; x++ {
+			srcX := bounds.Min.X + x*srcW/dstW
+			dst.Set(x, y, img.At(srcX, srcY))
+		}
+	}
+	return dst
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,103 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// encodeTestPNG returns a w x h solid-color PNG, for feeding GenerateDataURI
+// a decodable image without needing a fixture file on disk.
+func encodeTestPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 0, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode test PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestGenerateDataURI_ResizesLargeImage tests that a PNG larger than
+// MaxDimension comes back as a JPEG data URI whose decoded bounds fit
+// within MaxDimension.
+func TestGenerateDataURI_ResizesLargeImage(t *testing.T) {
+	uri, ok := GenerateDataURI("image/png", encodeTestPNG(t, 600, 300))
+	if !ok {
+		t.Fatal("expected a thumbnail to be generated")
+	}
+	if !strings.HasPrefix(uri, "data:image/jpeg;base64,") {
+		t.Errorf("expected a JPEG data URI, got prefix of %q", uri[:min(40, len(uri))])
+	}
+
+	decoded := decodeDataURI(t, uri)
+	bounds := decoded.Bounds()
+	if bounds.Dx() > MaxDimension || bounds.Dy() > MaxDimension {
+		t.Errorf("expected thumbnail to fit within %dx%d, got %dx%d", MaxDimension, MaxDimension, bounds.Dx(), bounds.Dy())
+	}
+	if bounds.Dx() != MaxDimension && bounds.Dy() != MaxDimension {
+		t.Errorf("expected one dimension to exactly hit %d, got %dx%d", MaxDimension, bounds.Dx(), bounds.Dy())
+	}
+}
+
+// TestGenerateDataURI_SmallImageUnchangedSize tests that an image already
+// smaller than MaxDimension isn't upscaled.
+func TestGenerateDataURI_SmallImageUnchangedSize(t *testing.T) {
+	uri, ok := GenerateDataURI("image/png", encodeTestPNG(t, 20, 10))
+	if !ok {
+		t.Fatal("expected a thumbnail to be generated")
+	}
+
+	bounds := decodeDataURI(t, uri).Bounds()
+	if bounds.Dx() != 20 || bounds.Dy() != 10 {
+		t.Errorf("expected the original 20x10 size to be preserved, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+}
+
+// TestGenerateDataURI_UnsupportedMimeType tests that an unrecognized MIME
+// type returns ok=false rather than an error.
+func TestGenerateDataURI_UnsupportedMimeType(t *testing.T) {
+	if _, ok := GenerateDataURI("video/mp4", []byte("not an image")); ok {
+		t.Error("expected ok=false for a MIME type with no registered Generator")
+	}
+}
+
+// TestGenerateDataURI_UndecodableData tests that data claiming to be an
+// image but failing to decode returns ok=false rather than an error.
+func TestGenerateDataURI_UndecodableData(t *testing.T) {
+	if _, ok := GenerateDataURI("image/png", []byte("this is not really a png")); ok {
+		t.Error("expected ok=false for data that fails to decode")
+	}
+}
+
+func decodeDataURI(t *testing.T, uri string) image.Image {
+	t.Helper()
+
+	const prefix = "data:image/jpeg;base64,"
+	if !strings.HasPrefix(uri, prefix) {
+		t.Fatalf("expected data URI prefix %q, got %q", prefix, uri)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(uri[len(prefix):])
+	if err != nil {
+		t.Fatalf("failed to base64-decode data URI: %v", err)
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("failed to decode thumbnail JPEG: %v", err)
+	}
+	return img
+}
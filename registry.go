@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// transferRegistry is the single owner of the transfer list and the
+// overwrite-response channels keyed by transfer ID. Everything that used
+// to read/mutate App's transfers and overwriteResponses fields through the
+// App's embedded sync.RWMutex now goes through here instead, so a
+// concurrent Len/GetTransfers can never race with a SendFile/ReceiveFile
+// append (a slice append can reallocate the backing array out from under
+// a reader), and so IDs survive future removals instead of colliding once
+// len(transfers) stops increasing monotonically.
+//
+// transfers stores *FileTransfer rather than FileTransfer so that a
+// pointer returned by Add/Get stays valid for the life of the transfer:
+// Add/Snapshot only ever append/copy the outer slice of pointers, never
+// the FileTransfer values themselves, so reallocating that slice can't
+// strand a pointer an in-flight transfer goroutine is still mutating.
+type transferRegistry struct {
+	mu sync.RWMutex
+
+	transfers          []*FileTransfer
+	overwriteResponses map[string]chan string
+	overwritePolicies  map[string]OverwritePolicy
+
+	nextSendID    uint64
+	nextReceiveID uint64
+}
+
+func newTransferRegistry() *transferRegistry {
+	return &transferRegistry{
+		overwriteResponses: make(map[string]chan string),
+		overwritePolicies:  make(map[string]OverwritePolicy),
+	}
+}
+
+// Add prepends transfer to the registry, preserving the existing
+// newest-first ordering, and returns a pointer into the registry's backing
+// slice for the transfer's goroutine to update in place as it progresses.
+func (r *transferRegistry) Add(transfer FileTransfer) *FileTransfer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := &transfer
+	r.transfers = append([]*FileTransfer{stored}, r.transfers...)
+	return stored
+}
+
+// Get returns a pointer to the transfer with the given id for in-place
+// status mutation, and whether it was found.
+func (r *transferRegistry) Get(id string) (*FileTransfer, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, t := range r.transfers {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Snapshot returns a copy of the transfer list, safe to range over without
+// racing a concurrent Add or a transfer goroutine's in-place mutation.
+func (r *transferRegistry) Snapshot() []FileTransfer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]FileTransfer, len(r.transfers))
+	for i, t := range r.transfers {
+		out[i] = *t
+	}
+	return out
+}
+
+// Len returns the number of transfers in the registry.
+func (r *transferRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.transfers)
+}
+
+// NextSendID returns a monotonic, collision-free ID for a new outbound
+// transfer, numbered from the count of sends started rather than
+// len(transfers), so removing a transfer can never hand out a duplicate ID.
+func (r *transferRegistry) NextSendID() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := fmt.Sprintf("send-%d", r.nextSendID)
+	r.nextSendID++
+	return id
+}
+
+// NextReceiveID returns a monotonic, collision-free ID for a new inbound
+// transfer, numbered the same way NextSendID is.
+func (r *transferRegistry) NextReceiveID() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := fmt.Sprintf("receive-%d", r.nextReceiveID)
+	r.nextReceiveID++
+	return id
+}
+
+// RegisterOverwriteResponse creates and stores the channel RespondToOverwrite
+// will deliver the user's decision on, for transferID.
+func (r *transferRegistry) RegisterOverwriteResponse(transferID string) chan string {
+	ch := make(chan string)
+
+	r.mu.Lock()
+	r.overwriteResponses[transferID] = ch
+	r.mu.Unlock()
+
+	return ch
+}
+
+// RespondToOverwrite delivers response on transferID's overwrite channel, if
+// one is currently registered, then forgets it.
+func (r *transferRegistry) RespondToOverwrite(transferID, response string) {
+	r.mu.RLock()
+	ch, ok := r.overwriteResponses[transferID]
+	r.mu.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	ch <- response
+
+	r.mu.Lock()
+	delete(r.overwriteResponses, transferID)
+	r.mu.Unlock()
+}
+
+// SetOverwritePolicy records policy as transferID's per-transfer override,
+// taking precedence over the App's global policy for the rest of that
+// transfer. Used both by SetTransferOverwritePolicy and by
+// RespondToOverwrite's applyToAll parameter.
+func (r *transferRegistry) SetOverwritePolicy(transferID string, policy OverwritePolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.overwritePolicies[transferID] = policy
+}
+
+// OverwritePolicy returns the per-transfer override for transferID set by
+// SetOverwritePolicy, and whether one exists.
+func (r *transferRegistry) OverwritePolicy(transferID string) (OverwritePolicy, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	policy, ok := r.overwritePolicies[transferID]
+	return policy, ok
+}
@@ -0,0 +1,103 @@
+package main
+
+import "testing"
+
+// TestTransferRegistry_AddIsNewestFirst tests that Add prepends, matching
+// the ordering GetTransfers previously got from appending to the front of
+// a.transfers directly.
+func TestTransferRegistry_AddIsNewestFirst(t *testing.T) {
+	r := newTransferRegistry()
+	r.Add(FileTransfer{ID: "first"})
+	r.Add(FileTransfer{ID: "second"})
+
+	snapshot := r.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("expected 2 transfers, got %d", len(snapshot))
+	}
+	if snapshot[0].ID != "second" || snapshot[1].ID != "first" {
+		t.Errorf("expected [second, first], got [%s, %s]", snapshot[0].ID, snapshot[1].ID)
+	}
+}
+
+// TestTransferRegistry_Get tests that Get returns a pointer into the
+// registry's own storage, so mutating it is visible to later Get/Snapshot
+// calls.
+func TestTransferRegistry_Get(t *testing.T) {
+	r := newTransferRegistry()
+	r.Add(FileTransfer{ID: "a", Status: FileTransferStatusPreparing})
+
+	transfer, ok := r.Get("a")
+	if !ok {
+		t.Fatal("expected to find transfer a")
+	}
+	transfer.Status = FileTransferStatusCompleted
+
+	updated, _ := r.Get("a")
+	if updated.Status != FileTransferStatusCompleted {
+		t.Errorf("expected mutation through Get's pointer to stick, got %s", updated.Status)
+	}
+
+	if _, ok := r.Get("missing"); ok {
+		t.Error("expected Get to report not found for an unknown id")
+	}
+}
+
+// TestTransferRegistry_AddKeepsEarlierPointersLive tests that a pointer
+// returned by Add survives later Add calls: Add must never reallocate the
+// FileTransfer values themselves, only the outer slice of pointers to
+// them, or a transfer goroutine mutating through an earlier pointer would
+// silently stop being visible to Get/Snapshot as soon as another transfer
+// started.
+func TestTransferRegistry_AddKeepsEarlierPointersLive(t *testing.T) {
+	r := newTransferRegistry()
+
+	first := r.Add(FileTransfer{ID: "a", Status: FileTransferStatusPreparing})
+	r.Add(FileTransfer{ID: "b", Status: FileTransferStatusPreparing})
+
+	first.Status = FileTransferStatusCompleted
+
+	updated, ok := r.Get("a")
+	if !ok {
+		t.Fatal("expected to find transfer a")
+	}
+	if updated.Status != FileTransferStatusCompleted {
+		t.Errorf("expected mutation through a pointer from an earlier Add to stick, got %s", updated.Status)
+	}
+}
+
+// TestTransferRegistry_NextIDsAreMonotonicAndIndependent tests that
+// NextSendID/NextReceiveID never repeat and don't depend on the current
+// length of the transfer list, unlike the old len(transfers) scheme.
+func TestTransferRegistry_NextIDsAreMonotonicAndIndependent(t *testing.T) {
+	r := newTransferRegistry()
+
+	if id := r.NextSendID(); id != "send-0" {
+		t.Errorf("expected send-0, got %s", id)
+	}
+	if id := r.NextSendID(); id != "send-1" {
+		t.Errorf("expected send-1, got %s", id)
+	}
+	if id := r.NextReceiveID(); id != "receive-0" {
+		t.Errorf("expected receive-0, got %s", id)
+	}
+}
+
+// TestTransferRegistry_OverwriteResponse tests that RespondToOverwrite
+// delivers to a channel registered with RegisterOverwriteResponse and
+// forgets it afterward.
+func TestTransferRegistry_OverwriteResponse(t *testing.T) {
+	r := newTransferRegistry()
+
+	// No-op for an unregistered transfer
+	r.RespondToOverwrite("unknown", "yes")
+
+	ch := r.RegisterOverwriteResponse("t1")
+	go r.RespondToOverwrite("t1", "yes")
+
+	if response := <-ch; response != "yes" {
+		t.Errorf("expected yes, got %s", response)
+	}
+	if _, ok := r.overwriteResponses["t1"]; ok {
+		t.Error("expected the channel to be forgotten after responding")
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/schollz/croc/v10/src/tcp"
+)
+
+// RelayEndpoint describes a croc relay server that transfers can route
+// through.
+type RelayEndpoint struct {
+	Name     string   `json:"name"`
+	Address  string   `json:"address"`
+	Ports    []string `json:"ports"`
+	Password string   `json:"password"`
+}
+
+// EndpointSource resolves which relay endpoint to use for a transfer.
+// operation is "send" or "receive", mirroring git-lfs's EndpointSource,
+// since in principle a source could point sends and receives at different
+// relays.
+type EndpointSource interface {
+	Endpoint(operation string) RelayEndpoint
+}
+
+// defaultRelay is schollz's public relay; it's what every send/receive
+// hardcoded before relays became configurable, and remains the fallback
+// when the user hasn't configured one of their own.
+var defaultRelay = RelayEndpoint{
+	Name:     "schollz",
+	Address:  "croc.schollz.com:9009",
+	Ports:    []string{"9009", "9010", "9011", "9012", "9013"},
+	Password: "pass123",
+}
+
+// relayConfigFile is the name of the JSON file persisted under the user's
+// config dir.
+const relayConfigFile = "relays.json"
+
+// relayStore is the default EndpointSource: it persists named relays to a
+// JSON file in the user's config dir and falls back to defaultRelay until
+// the user adds their own.
+type relayStore struct {
+	mu      sync.Mutex
+	path    string
+	relays  map[string]RelayEndpoint
+	current string
+}
+
+// relayStoreFile is the on-disk JSON shape for relayStore.
+type relayStoreFile struct {
+	Relays       map[string]RelayEndpoint `json:"relays"`
+	DefaultRelay string                   `json:"defaultRelay"`
+}
+
+// newRelayStore loads relays.json from the user's config dir, creating an
+// in-memory store seeded with defaultRelay if the file doesn't exist yet.
+func newRelayStore() (*relayStore, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve user config dir")
+	}
+	return loadRelayStore(filepath.Join(configDir, "krokodyl", relayConfigFile))
+}
+
+func loadRelayStore(path string) (*relayStore, error) {
+	s := &relayStore{
+		path:    path,
+		relays:  map[string]RelayEndpoint{defaultRelay.Name: defaultRelay},
+		current: defaultRelay.Name,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, errors.Wrapf(err, "failed to read relay config: %s", path)
+	}
+
+	var file relayStoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse relay config: %s", path)
+	}
+
+	if len(file.Relays) > 0 {
+		s.relays = file.Relays
+	}
+	if file.DefaultRelay != "" {
+		s.current = file.DefaultRelay
+	}
+	return s, nil
+}
+
+func (s *relayStore) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return errors.Wrapf(err, "failed to create config dir for %s", s.path)
+	}
+
+	data, err := json.MarshalIndent(relayStoreFile{Relays: s.relays, DefaultRelay: s.current}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal relay config")
+	}
+
+	if err := os.WriteFile(s.path, data, 0o644); err != nil {
+		return errors.Wrapf(err, "failed to write relay config: %s", s.path)
+	}
+	return nil
+}
+
+// Endpoint implements EndpointSource, returning the configured default
+// relay regardless of operation; send and receive share the same relay
+// unless the caller picks one explicitly by name.
+func (s *relayStore) Endpoint(operation string) RelayEndpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.relays[s.current]; ok {
+		return e
+	}
+	return defaultRelay
+}
+
+// byName looks up a relay by name, falling back to the default endpoint if
+// name is empty or unknown.
+func (s *relayStore) byName(name string) RelayEndpoint {
+	if name == "" {
+		return s.Endpoint("")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.relays[name]; ok {
+		return e
+	}
+	return defaultRelay
+}
+
+// add saves or updates a named relay endpoint.
+func (s *relayStore) add(endpoint RelayEndpoint) error {
+	if endpoint.Name == "" {
+		return errors.New("relay name must not be empty")
+	}
+
+	s.mu.Lock()
+	s.relays[endpoint.Name] = endpoint
+	s.mu.Unlock()
+
+	return s.save()
+}
+
+// remove deletes a named relay endpoint, resetting the default back to
+// defaultRelay if it was the one removed.
+func (s *relayStore) remove(name string) error {
+	s.mu.Lock()
+	delete(s.relays, name)
+	if s.current == name {
+		s.current = defaultRelay.Name
+		s.relays[defaultRelay.Name] = defaultRelay
+	}
+	s.mu.Unlock()
+
+	return s.save()
+}
+
+// list returns every configured relay endpoint.
+func (s *relayStore) list() []RelayEndpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoints := make([]RelayEndpoint, 0, len(s.relays))
+	for _, e := range s.relays {
+		endpoints = append(endpoints, e)
+	}
+	return endpoints
+}
+
+// setDefault selects which configured relay Endpoint returns.
+func (s *relayStore) setDefault(name string) error {
+	s.mu.Lock()
+	if _, ok := s.relays[name]; !ok {
+		s.mu.Unlock()
+		return errors.Errorf("unknown relay: %s", name)
+	}
+	s.current = name
+	s.mu.Unlock()
+
+	return s.save()
+}
+
+// AddRelay adds or updates a relay endpoint and persists it to disk.
+func (a *App) AddRelay(endpoint RelayEndpoint) error {
+	return a.endpoints.add(endpoint)
+}
+
+// RemoveRelay removes a configured relay by name.
+func (a *App) RemoveRelay(name string) error {
+	return a.endpoints.remove(name)
+}
+
+// ListRelays returns every relay the user has configured.
+func (a *App) ListRelays() []RelayEndpoint {
+	return a.endpoints.list()
+}
+
+// SetDefaultRelay selects which configured relay send/receive use when no
+// relay name is given explicitly.
+func (a *App) SetDefaultRelay(name string) error {
+	return a.endpoints.setDefault(name)
+}
+
+// TestRelay dials addr and performs croc's ping/pong handshake, returning
+// an error if the relay didn't answer correctly. It's meant to let a user
+// validate a relay address before saving it with AddRelay.
+func (a *App) TestRelay(addr string) error {
+	if err := tcp.PingServer(addr); err != nil {
+		return errors.Wrapf(err, "relay %s did not respond to the croc handshake", addr)
+	}
+	return nil
+}
+
+// resolveRelay picks the relay endpoint a transfer should use: the named
+// relay if relayName is non-empty, otherwise the configured default for
+// operation ("send" or "receive").
+func (a *App) resolveRelay(relayName, operation string) RelayEndpoint {
+	if relayName != "" {
+		return a.endpoints.byName(relayName)
+	}
+	return a.endpoints.Endpoint(operation)
+}
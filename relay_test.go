@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestRelayStore_DefaultsWithoutConfigFile tests that a store pointed at a
+// non-existent config file falls back to the schollz default.
+func TestRelayStore_DefaultsWithoutConfigFile(t *testing.T) {
+	store, err := loadRelayStore(filepath.Join(t.TempDir(), "relays.json"))
+	if err != nil {
+		t.Fatalf("unexpected error loading store: %v", err)
+	}
+
+	got := store.Endpoint("send")
+	if !reflect.DeepEqual(got, defaultRelay) {
+		t.Errorf("expected default relay, got %+v", got)
+	}
+}
+
+// TestRelayStore_AddListRemove tests that relays persist and round-trip
+// through a fresh store loaded from the same path.
+func TestRelayStore_AddListRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relays.json")
+	store, err := loadRelayStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading store: %v", err)
+	}
+
+	school := RelayEndpoint{Name: "school", Address: "relay.school.edu:9009", Ports: []string{"9009"}, Password: "secret"}
+	if err := store.add(school); err != nil {
+		t.Fatalf("unexpected error adding relay: %v", err)
+	}
+
+	reloaded, err := loadRelayStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error reloading store: %v", err)
+	}
+
+	if got := reloaded.byName("school"); !reflect.DeepEqual(got, school) {
+		t.Errorf("expected reloaded relay %+v, got %+v", school, got)
+	}
+
+	if len(reloaded.list()) != 2 {
+		t.Errorf("expected 2 relays (default + school), got %d", len(reloaded.list()))
+	}
+
+	if err := reloaded.remove("school"); err != nil {
+		t.Fatalf("unexpected error removing relay: %v", err)
+	}
+	if got := reloaded.byName("school"); !reflect.DeepEqual(got, defaultRelay) {
+		t.Errorf("expected removed relay to fall back to default, got %+v", got)
+	}
+}
+
+// TestRelayStore_SetDefault tests switching which relay Endpoint returns.
+func TestRelayStore_SetDefault(t *testing.T) {
+	store, err := loadRelayStore(filepath.Join(t.TempDir(), "relays.json"))
+	if err != nil {
+		t.Fatalf("unexpected error loading store: %v", err)
+	}
+
+	school := RelayEndpoint{Name: "school", Address: "relay.school.edu:9009", Ports: []string{"9009"}, Password: "secret"}
+	if err := store.add(school); err != nil {
+		t.Fatalf("unexpected error adding relay: %v", err)
+	}
+
+	if err := store.setDefault("school"); err != nil {
+		t.Fatalf("unexpected error setting default: %v", err)
+	}
+	if got := store.Endpoint("send"); !reflect.DeepEqual(got, school) {
+		t.Errorf("expected default to be school, got %+v", got)
+	}
+
+	if err := store.setDefault("unknown"); err == nil {
+		t.Error("expected error setting unknown relay as default")
+	}
+}
+
+// TestApp_RelayMethods tests the Wails-bound relay management methods.
+func TestApp_RelayMethods(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+
+	custom := RelayEndpoint{Name: "custom", Address: "127.0.0.1:9009", Ports: []string{"9009"}, Password: "p"}
+	if err := app.AddRelay(custom); err != nil {
+		t.Fatalf("unexpected error adding relay: %v", err)
+	}
+
+	found := false
+	for _, e := range app.ListRelays() {
+		if reflect.DeepEqual(e, custom) {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected ListRelays to include the added relay")
+	}
+
+	if err := app.SetDefaultRelay("custom"); err != nil {
+		t.Fatalf("unexpected error setting default relay: %v", err)
+	}
+	if got := app.resolveRelay("", "send"); !reflect.DeepEqual(got, custom) {
+		t.Errorf("expected resolveRelay to return custom relay, got %+v", got)
+	}
+	if got := app.resolveRelay("nonexistent", "send"); !reflect.DeepEqual(got, defaultRelay) {
+		t.Errorf("expected resolveRelay to fall back to default for unknown name, got %+v", got)
+	}
+
+	if err := app.RemoveRelay("custom"); err != nil {
+		t.Fatalf("unexpected error removing relay: %v", err)
+	}
+	if got := app.resolveRelay("", "send"); !reflect.DeepEqual(got, defaultRelay) {
+		t.Errorf("expected default relay after removal, got %+v", got)
+	}
+}
+
+// TestApp_TestRelay tests that TestRelay reports an error for an address
+// with nothing listening on it.
+func TestApp_TestRelay(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+
+	if err := app.TestRelay("127.0.0.1:1"); err == nil {
+		t.Error("expected an error pinging a relay with nothing listening")
+	}
+}
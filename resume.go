@@ -0,0 +1,220 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ResumableState captures enough about an interrupted receive to pick the
+// transfer back up with ResumeInterruptedTransfer instead of starting over.
+// croc itself already resumes at the chunk level when it finds a partial
+// file at the path it's about to write (see src/utils.MissingChunks); what's
+// missing, and what this adds, is remembering which tempDir that partial
+// file is sitting in and a fingerprint of it, across a process restart.
+type ResumableState struct {
+	TransferID      string `json:"transferId"`
+	Code            string `json:"code"`
+	DestinationPath string `json:"destinationPath"`
+	RelayName       string `json:"relayName"`
+	TempDir         string `json:"tempDir"`
+
+	// PartialHashes maps each partial file's name (within TempDir) to a
+	// sha256Prefix fingerprint, so ResumeInterruptedTransfer can tell a
+	// tempDir that's still the one croc left behind from one that's been
+	// cleared or reused since, before handing it back to croc.
+	PartialHashes map[string]string `json:"partialHashes"`
+}
+
+// resumableConfigFile is the name of the JSON file persisted under the
+// user's config dir, alongside relay.go's relays.json and config.go's
+// config.json.
+const resumableConfigFile = "resumable.json"
+
+// resumeStore persists ResumableState by transfer ID, the same way
+// relayStore persists RelayEndpoint by name.
+type resumeStore struct {
+	mu     sync.Mutex
+	path   string
+	states map[string]ResumableState
+}
+
+// newResumeStore loads resumable.json from the user's config dir, starting
+// from an empty store if the file doesn't exist yet.
+func newResumeStore() (*resumeStore, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve user config dir")
+	}
+	return loadResumeStore(filepath.Join(configDir, "krokodyl", resumableConfigFile))
+}
+
+func loadResumeStore(path string) (*resumeStore, error) {
+	s := &resumeStore{
+		path:   path,
+		states: make(map[string]ResumableState),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, errors.Wrapf(err, "failed to read resumable transfer state: %s", path)
+	}
+
+	if err := json.Unmarshal(data, &s.states); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse resumable transfer state: %s", path)
+	}
+	return s, nil
+}
+
+func (s *resumeStore) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return errors.Wrapf(err, "failed to create config dir for %s", s.path)
+	}
+
+	data, err := json.MarshalIndent(s.states, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal resumable transfer state")
+	}
+
+	if err := os.WriteFile(s.path, data, 0o644); err != nil {
+		return errors.Wrapf(err, "failed to write resumable transfer state: %s", s.path)
+	}
+	return nil
+}
+
+// put saves or updates state, keyed by its TransferID.
+func (s *resumeStore) put(state ResumableState) error {
+	s.mu.Lock()
+	s.states[state.TransferID] = state
+	s.mu.Unlock()
+
+	return s.save()
+}
+
+// get returns the resumable state for transferID, and whether one exists.
+func (s *resumeStore) get(transferID string) (ResumableState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[transferID]
+	return state, ok
+}
+
+// remove forgets the resumable state for transferID, e.g. once it's either
+// picked back up or completed by other means.
+func (s *resumeStore) remove(transferID string) error {
+	s.mu.Lock()
+	delete(s.states, transferID)
+	s.mu.Unlock()
+
+	return s.save()
+}
+
+// resumablePrefixSize is how many bytes of a partial file sha256Prefix
+// hashes, as a cheap fingerprint rather than re-hashing the whole (possibly
+// large, still-incomplete) file.
+const resumablePrefixSize = 64 * 1024
+
+// sha256Prefix hashes the first n bytes of the file at path (or the whole
+// file, if it's shorter than n), so a caller can cheaply tell whether a
+// partial file found later is still the one it left behind.
+func sha256Prefix(fsys FS, path string, n int64) (string, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to open %s", path)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, io.LimitReader(f, n)); err != nil {
+		return "", errors.Wrapf(err, "failed to hash %s", path)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// buildResumableState fingerprints tempDir's partial files into a
+// ResumableState for transfer, so ResumeInterruptedTransfer can continue it
+// later. ok is false if tempDir has nothing worth resuming, e.g. croc never
+// got far enough to write anything.
+func (a *App) buildResumableState(transfer *FileTransfer, code, destinationPath, relayName, tempDir string) (ResumableState, bool) {
+	infos, err := listFiles(a.fs, tempDir)
+	if err != nil || len(infos) == 0 {
+		return ResumableState{}, false
+	}
+
+	hashes := make(map[string]string, len(infos))
+	for path, info := range infos {
+		prefix, err := sha256Prefix(a.fs, path, resumablePrefixSize)
+		if err != nil {
+			logrus.WithError(err).Warnf("could not fingerprint partial file %s, leaving it out of resumable state", path)
+			continue
+		}
+		hashes[info.Name()] = prefix
+	}
+	if len(hashes) == 0 {
+		return ResumableState{}, false
+	}
+
+	return ResumableState{
+		TransferID:      transfer.ID,
+		Code:            code,
+		DestinationPath: destinationPath,
+		RelayName:       relayName,
+		TempDir:         tempDir,
+		PartialHashes:   hashes,
+	}, true
+}
+
+// ResumeInterruptedTransfer continues a receive transfer left at
+// FileTransferStatusResumable by a prior interrupted attempt, reusing its
+// tempDir so croc's own chunk-resume logic (see ResumableState) picks up
+// from whatever partial files are still on disk there instead of
+// re-downloading from scratch. It's distinct from control.go's
+// ResumeTransfer, which un-pauses a transfer still running in this process;
+// ResumeInterruptedTransfer instead restarts one that crashed, or was left
+// behind by a previous run of the app entirely, from persisted state.
+//
+// Only receives can be resumed this way: croc's chunk-resume is driven by
+// what's already on disk at the write destination, which only a receive has.
+func (a *App) ResumeInterruptedTransfer(transferID string) (string, error) {
+	state, ok := a.resumable.get(transferID)
+	if !ok {
+		return "", errors.Errorf("no resumable transfer with id %s", transferID)
+	}
+
+	for name, wantPrefix := range state.PartialHashes {
+		gotPrefix, err := sha256Prefix(a.fs, filepath.Join(state.TempDir, name), resumablePrefixSize)
+		if err != nil || gotPrefix != wantPrefix {
+			a.resumable.remove(transferID)
+			a.fs.RemoveAll(state.TempDir)
+			return "", errors.Errorf("partial file %s changed or went missing since the interrupted attempt; start a new receive instead", name)
+		}
+	}
+
+	transfer, ok := a.registry.Get(transferID)
+	if !ok {
+		return "", errors.Errorf("no transfer record with id %s", transferID)
+	}
+
+	if err := a.resumable.remove(transferID); err != nil {
+		return "", err
+	}
+
+	transfer.Status = FileTransferStatusPreparing
+	transfer.Name = "Resuming receive..."
+	a.emit(a.ctx, TransferEventUpdated, transfer)
+
+	go a.performReceive(a.ctx, transfer, state.Code, state.DestinationPath, state.RelayName, state.TempDir)
+
+	return transferID, nil
+}
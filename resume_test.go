@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestResumeStore_PutGetRemove_Persists tests that a saved ResumableState
+// round-trips through a fresh store loaded from the same path, and that
+// remove clears it again.
+func TestResumeStore_PutGetRemove_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resumable.json")
+	store, err := loadResumeStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading store: %v", err)
+	}
+
+	state := ResumableState{
+		TransferID:      "receive-0",
+		Code:            "1234-code",
+		DestinationPath: "/dest",
+		TempDir:         "/tmp/krokodyl-1",
+		PartialHashes:   map[string]string{"video.mp4": "deadbeef"},
+	}
+	if err := store.put(state); err != nil {
+		t.Fatalf("unexpected error putting state: %v", err)
+	}
+
+	reloaded, err := loadResumeStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error reloading store: %v", err)
+	}
+	got, ok := reloaded.get("receive-0")
+	if !ok {
+		t.Fatal("expected reloaded store to have receive-0")
+	}
+	if got.Code != state.Code || got.TempDir != state.TempDir || got.PartialHashes["video.mp4"] != "deadbeef" {
+		t.Errorf("expected reloaded state %+v, got %+v", state, got)
+	}
+
+	if err := reloaded.remove("receive-0"); err != nil {
+		t.Fatalf("unexpected error removing state: %v", err)
+	}
+	if _, ok := reloaded.get("receive-0"); ok {
+		t.Error("expected receive-0 to be gone after remove")
+	}
+}
+
+// TestSha256Prefix tests that hashing the same bytes twice agrees, and that
+// a shorter file than n is hashed in full rather than erroring.
+func TestSha256Prefix(t *testing.T) {
+	fsys := newMemFS()
+	fsys.writeFile("/tmp/partial.bin", []byte("some partial bytes"))
+
+	got, err := sha256Prefix(fsys, "/tmp/partial.bin", resumablePrefixSize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, err := sha256Prefix(fsys, "/tmp/partial.bin", resumablePrefixSize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("expected hashing the same file twice to agree, got %s vs %s", got, want)
+	}
+
+	if _, err := sha256Prefix(fsys, "/tmp/nonexistent.bin", resumablePrefixSize); err == nil {
+		t.Error("expected an error hashing a missing file")
+	}
+}
+
+// TestApp_BuildResumableState tests that buildResumableState fingerprints
+// whatever partial files are in tempDir, and reports ok=false for an empty
+// tempDir.
+func TestApp_BuildResumableState(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+	fsys := newMemFS()
+	app.fs = fsys
+
+	transfer := &FileTransfer{ID: "receive-0"}
+
+	if _, ok := app.buildResumableState(transfer, "code", "/dest", "", "/tmp/krokodyl-empty"); ok {
+		t.Error("expected ok=false for a tempDir with nothing in it")
+	}
+
+	fsys.writeFile("/tmp/krokodyl-1/video.mp4", []byte("partial bytes from a crashed receive"))
+
+	state, ok := app.buildResumableState(transfer, "code", "/dest", "myrelay", "/tmp/krokodyl-1")
+	if !ok {
+		t.Fatal("expected ok=true for a tempDir with a partial file")
+	}
+	if state.TransferID != "receive-0" || state.Code != "code" || state.DestinationPath != "/dest" || state.RelayName != "myrelay" {
+		t.Errorf("unexpected state: %+v", state)
+	}
+	if _, ok := state.PartialHashes["video.mp4"]; !ok {
+		t.Errorf("expected a fingerprint for video.mp4, got %+v", state.PartialHashes)
+	}
+}
+
+// TestApp_ResumeInterruptedTransfer_MismatchedPartial tests that a partial
+// file which changed since the crash (a different fingerprint than what was
+// persisted) is rejected rather than handed back to croc, and that the
+// stale state and tempDir are cleaned up.
+func TestApp_ResumeInterruptedTransfer_MismatchedPartial(t *testing.T) {
+	fsys := newMemFS()
+	app := &App{fs: fsys}
+	app.startup(context.Background())
+	app.fs = fsys
+
+	fsys.writeFile("/tmp/krokodyl-1/video.mp4", []byte("original partial bytes"))
+	transfer := FileTransfer{ID: "receive-0", Code: "1234-code", Status: FileTransferStatusResumable}
+	app.registry.Add(transfer)
+
+	if err := app.resumable.put(ResumableState{
+		TransferID:      "receive-0",
+		Code:            "1234-code",
+		DestinationPath: "/dest",
+		TempDir:         "/tmp/krokodyl-1",
+		PartialHashes:   map[string]string{"video.mp4": strings.Repeat("0", 64)},
+	}); err != nil {
+		t.Fatalf("unexpected error persisting state: %v", err)
+	}
+
+	if _, err := app.ResumeInterruptedTransfer("receive-0"); err == nil {
+		t.Error("expected an error for a partial file whose fingerprint no longer matches")
+	}
+
+	if _, ok := app.resumable.get("receive-0"); ok {
+		t.Error("expected the stale resumable state to be removed")
+	}
+}
+
+// TestApp_ResumeInterruptedTransfer_Unknown tests that resuming an unknown
+// transfer ID returns an error instead of panicking.
+func TestApp_ResumeInterruptedTransfer_Unknown(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+
+	if _, err := app.ResumeInterruptedTransfer("nonexistent"); err == nil {
+		t.Error("expected an error for an unknown transfer id")
+	}
+}
+
+// TestApp_ResumeInterruptedTransfer_Integration simulates a mid-transfer
+// crash (a partial file plus sidecar ResumableState left behind by a prior
+// performReceive) and asserts ResumeInterruptedTransfer picks the transfer
+// back up: it clears the persisted state, restores the transfer to
+// FileTransferStatusPreparing, and hands the same tempDir back to
+// performReceive so croc resumes from whatever bytes are already there,
+// rather than starting over. As with TestReceiveFile_Integration, it doesn't
+// wait for the spawned goroutine, which needs a live relay to get further.
+func TestApp_ResumeInterruptedTransfer_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	fsys := newMemFS()
+	app := &App{fs: fsys, emit: func(context.Context, string, ...interface{}) {}}
+	app.startup(context.Background())
+	app.fs = fsys
+
+	fsys.writeFile("/tmp/krokodyl-1/movie.mp4", []byte("first half of the file"))
+	prefix, err := sha256Prefix(fsys, "/tmp/krokodyl-1/movie.mp4", resumablePrefixSize)
+	if err != nil {
+		t.Fatalf("unexpected error fingerprinting: %v", err)
+	}
+
+	app.registry.Add(FileTransfer{ID: "receive-0", Code: "1234-code", Status: FileTransferStatusResumable})
+	if err := app.resumable.put(ResumableState{
+		TransferID:      "receive-0",
+		Code:            "1234-code",
+		DestinationPath: "/dest",
+		TempDir:         "/tmp/krokodyl-1",
+		PartialHashes:   map[string]string{"movie.mp4": prefix},
+	}); err != nil {
+		t.Fatalf("unexpected error persisting state: %v", err)
+	}
+
+	transferID, err := app.ResumeInterruptedTransfer("receive-0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transferID != "receive-0" {
+		t.Errorf("expected the same transfer id back, got %s", transferID)
+	}
+
+	if _, ok := app.resumable.get("receive-0"); ok {
+		t.Error("expected resumable state to be cleared once resumed")
+	}
+
+	stored, ok := app.registry.Get("receive-0")
+	if !ok {
+		t.Fatal("expected the transfer to still be in the registry")
+	}
+	if stored.Status != FileTransferStatusPreparing {
+		t.Errorf("expected status preparing while the resumed receive spins up, got %s", stored.Status)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// transferStatsSampleHz is how often in-flight transfers are sampled
+	// and transfer:stats events are emitted.
+	transferStatsSampleHz = 4
+
+	// transferStatsEmaAlpha is the weight given to the newest sample when
+	// smoothing instantaneous throughput into CurrentBps.
+	transferStatsEmaAlpha = 0.3
+
+	// globalStatsID is the key GetAllStats uses for the aggregate across
+	// all transfers with recorded accounting data, mirroring rclone's
+	// default stats group.
+	globalStatsID = "global"
+)
+
+// TransferStats carries live rate information for a single transfer, or
+// for the global aggregate across all transfers.
+type TransferStats struct {
+	BytesTransferred int64   `json:"bytesTransferred"`
+	TotalBytes       int64   `json:"totalBytes"`
+	CurrentBps       float64 `json:"currentBps"`
+	PeakBps          float64 `json:"peakBps"`
+	ETASeconds       float64 `json:"etaSeconds"`
+	ElapsedSeconds   float64 `json:"elapsedSeconds"`
+}
+
+// TransferStatsEvent is the payload emitted on TransferEventStats.
+type TransferStatsEvent struct {
+	TransferID string        `json:"transferId"`
+	Stats      TransferStats `json:"stats"`
+}
+
+// statsGroup accumulates accounting state for one transfer: the bytes seen
+// so far, an exponentially weighted moving average of throughput sampled
+// in ~1s buckets, and the peak rate observed.
+type statsGroup struct {
+	mu         sync.Mutex
+	start      time.Time
+	lastSample time.Time
+	lastBytes  int64
+	bytes      int64
+	total      int64
+	currentBps float64
+	peakBps    float64
+}
+
+func newStatsGroup() *statsGroup {
+	now := time.Now()
+	return &statsGroup{start: now, lastSample: now}
+}
+
+// update records a new (bytesTransferred, totalBytes) sample, folding the
+// instantaneous rate since the last sample into the moving average.
+func (g *statsGroup) update(bytesTransferred, totalBytes int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	if elapsed := now.Sub(g.lastSample).Seconds(); elapsed > 0 {
+		instBps := float64(bytesTransferred-g.lastBytes) / elapsed
+		g.currentBps = transferStatsEmaAlpha*instBps + (1-transferStatsEmaAlpha)*g.currentBps
+		if g.currentBps > g.peakBps {
+			g.peakBps = g.currentBps
+		}
+	}
+
+	g.lastSample = now
+	g.lastBytes = bytesTransferred
+	g.bytes = bytesTransferred
+	g.total = totalBytes
+}
+
+// snapshot returns the current stats for this group.
+func (g *statsGroup) snapshot() TransferStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stats := TransferStats{
+		BytesTransferred: g.bytes,
+		TotalBytes:       g.total,
+		CurrentBps:       g.currentBps,
+		PeakBps:          g.peakBps,
+		ElapsedSeconds:   time.Since(g.start).Seconds(),
+	}
+	if g.currentBps > 0 && g.total > g.bytes {
+		stats.ETASeconds = float64(g.total-g.bytes) / g.currentBps
+	}
+	return stats
+}
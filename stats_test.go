@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestStatsGroup_UpdateAndSnapshot tests rate and ETA computation
+func TestStatsGroup_UpdateAndSnapshot(t *testing.T) {
+	g := newStatsGroup()
+	g.lastSample = g.lastSample.Add(-time.Second)
+
+	g.update(1000, 10000)
+
+	stats := g.snapshot()
+	if stats.BytesTransferred != 1000 {
+		t.Errorf("expected 1000 bytes transferred, got %d", stats.BytesTransferred)
+	}
+	if stats.TotalBytes != 10000 {
+		t.Errorf("expected 10000 total bytes, got %d", stats.TotalBytes)
+	}
+	if stats.CurrentBps <= 0 {
+		t.Errorf("expected positive throughput, got %f", stats.CurrentBps)
+	}
+	if stats.PeakBps < stats.CurrentBps {
+		t.Errorf("peak should be at least current, got peak=%f current=%f", stats.PeakBps, stats.CurrentBps)
+	}
+	if stats.ETASeconds <= 0 {
+		t.Errorf("expected positive ETA, got %f", stats.ETASeconds)
+	}
+}
+
+// TestStatsGroup_PeakTracksMax tests that peak throughput never decreases
+func TestStatsGroup_PeakTracksMax(t *testing.T) {
+	g := newStatsGroup()
+
+	g.lastSample = g.lastSample.Add(-time.Second)
+	g.update(5000, 10000)
+	firstPeak := g.snapshot().PeakBps
+
+	g.lastSample = g.lastSample.Add(-time.Second)
+	g.update(5100, 10000) // much slower this bucket
+
+	if g.snapshot().PeakBps < firstPeak {
+		t.Errorf("peak should not decrease: had %f, now %f", firstPeak, g.snapshot().PeakBps)
+	}
+}
+
+// TestApp_GetTransferStats_Unknown tests the zero-value fallback
+func TestApp_GetTransferStats_Unknown(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+
+	stats := app.GetTransferStats("nonexistent")
+	if stats.BytesTransferred != 0 || stats.TotalBytes != 0 {
+		t.Errorf("expected zero-value stats, got %+v", stats)
+	}
+}
+
+// TestApp_GetAllStats_IncludesGlobal tests that the aggregate group sums
+// across all registered transfers
+func TestApp_GetAllStats_IncludesGlobal(t *testing.T) {
+	app := &App{}
+	app.startup(context.Background())
+
+	// Register a group directly rather than going through trackStats,
+	// which emits Wails runtime events that require a real lifecycle
+	// context (see the SendFile tests for the same constraint).
+	group := newStatsGroup()
+	group.update(500, 1000)
+	app.statsGroups["t1"] = group
+
+	all := app.GetAllStats()
+	if _, ok := all["t1"]; !ok {
+		t.Fatal("expected stats for t1")
+	}
+	global, ok := all[globalStatsID]
+	if !ok {
+		t.Fatal("expected a global aggregate entry")
+	}
+	if global.BytesTransferred != 500 {
+		t.Errorf("expected global bytes transferred 500, got %d", global.BytesTransferred)
+	}
+	if global.TotalBytes != 1000 {
+		t.Errorf("expected global total bytes 1000, got %d", global.TotalBytes)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io"
+	"net/http"
+
+	"p2p-ui/preview"
+)
+
+// maxPreviewSourceBytes bounds how much of a file generatePreview will read
+// into memory to decode a thumbnail from, so a multi-gigabyte file attached
+// by mistake doesn't get fully buffered just to discover it isn't one of
+// the formats preview.Generator handles today.
+const maxPreviewSourceBytes = 32 << 20 // 32MiB
+
+// generatePreview reads the file at path through fsys and returns a
+// thumbnail data URI for it, if its sniffed content type is one a
+// registered preview.Generator handles. ok is false for anything else
+// (most files, and anything larger than maxPreviewSourceBytes), not an
+// error, since not having a preview is the common case rather than a
+// failure worth surfacing.
+func generatePreview(fsys FS, path string) (string, bool) {
+	info, err := fsys.Stat(path)
+	if err != nil || info.Size() == 0 || info.Size() > maxPreviewSourceBytes {
+		return "", false
+	}
+
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", false
+	}
+
+	return preview.GenerateDataURI(http.DetectContentType(data), data)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// encodeTestPNG returns a small solid-color PNG, for feeding generatePreview
+// a decodable image without needing a fixture file on disk.
+func encodeTestPNG(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 20), G: uint8(y * 20), B: 0, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode test PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestGeneratePreview_Image tests that a recognized image file produces a
+// JPEG data URI.
+func TestGeneratePreview_Image(t *testing.T) {
+	fsys := newMemFS()
+	fsys.writeFile("/tmp/photo.png", encodeTestPNG(t))
+
+	uri, ok := generatePreview(fsys, "/tmp/photo.png")
+	if !ok {
+		t.Fatal("expected a preview to be generated for a PNG")
+	}
+	if !strings.HasPrefix(uri, "data:image/jpeg;base64,") {
+		t.Errorf("expected a JPEG data URI, got %q", uri)
+	}
+}
+
+// TestGeneratePreview_UnsupportedType tests that a file whose content isn't
+// a recognized image type returns ok=false rather than an error.
+func TestGeneratePreview_UnsupportedType(t *testing.T) {
+	fsys := newMemFS()
+	fsys.writeFile("/tmp/notes.txt", []byte("just some plain text"))
+
+	if _, ok := generatePreview(fsys, "/tmp/notes.txt"); ok {
+		t.Error("expected ok=false for a non-image file")
+	}
+}
+
+// TestGeneratePreview_MissingFile tests that a nonexistent path returns
+// ok=false instead of panicking.
+func TestGeneratePreview_MissingFile(t *testing.T) {
+	fsys := newMemFS()
+
+	if _, ok := generatePreview(fsys, "/tmp/nonexistent.png"); ok {
+		t.Error("expected ok=false for a missing file")
+	}
+}
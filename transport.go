@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/schollz/croc/v10/src/croc"
+	"github.com/schollz/croc/v10/src/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// Progress is a point-in-time snapshot reported by a Transport while
+// Offer/Accept's work is running. The final Progress for an attempt either
+// has Done set (success) or Err set (failure); the channel is closed
+// immediately after.
+type Progress struct {
+	BytesTransferred int64
+	TotalBytes       int64
+	Done             bool
+	Err              error
+
+	// Files is populated on Accept's final, successful Progress update with
+	// the sender-reported name/hash of each received file, for callers that
+	// need to verify file integrity (see expectedHash in hash.go). Offer
+	// never populates it; a Transport with no hash-reporting concept (e.g.
+	// lanTransport) leaves it nil.
+	Files []TransferredFile
+}
+
+// TransferredFile is the sender-reported name and hash of one file moved by
+// a Transport, reported on Accept's final Progress update.
+type TransferredFile struct {
+	Name string
+	Hash []byte
+}
+
+// Transport is one way of moving a file from a sender to a receiver: the
+// existing wormhole relay (wormholeTransport) or a direct LAN path
+// (lanTransport, see lan_transport.go). SetTransports configures the
+// ordered chain SendFile/ReceiveFile (via tryTransportsForSend and
+// tryTransportsForReceive) try a file against, falling back to the next
+// Transport if one errors or doesn't finish within its timeout.
+//
+// performReceive's resume (resume.go), policy (policy.go), and preview
+// (thumbnail.go) integrations stay outside the interface: they only need
+// filesystem access to whatever tempDir Accept wrote into (and, for resume,
+// croc's own habit of picking up a partial file already on disk at the path
+// it's about to write), so they compose unchanged around any Transport.
+type Transport interface {
+	// Name identifies this Transport, recorded on FileTransfer.TransportUsed.
+	Name() string
+
+	// Offer starts sending file, returning a code the receiving side
+	// passes to Accept to find it, and a channel of Progress updates
+	// terminated by a final update with Done or Err set.
+	Offer(ctx context.Context, file string) (code string, progress <-chan Progress, err error)
+
+	// Accept starts receiving whatever Offer(ctx, code) is sending
+	// elsewhere, writing it into destinationPath, and returns a channel of
+	// Progress updates the same way Offer does.
+	Accept(ctx context.Context, code, destinationPath string) (progress <-chan Progress, err error)
+}
+
+// defaultTransportTimeout bounds how long tryTransportsForSend/
+// tryTransportsForReceive wait for one Transport to complete before
+// falling back to the next configured Transport.
+const defaultTransportTimeout = 5 * time.Second
+
+// transportProgressPollInterval is how often wormholeTransport polls
+// croc's client for a Progress update while Send/Receive is running,
+// matching the poll-based approach app.go's trackStats already uses for
+// the legacy SendFile/ReceiveFile path.
+const transportProgressPollInterval = 200 * time.Millisecond
+
+// SetTransports replaces the ordered list of Transports
+// SendFileViaTransports/ReceiveFileViaTransports try, falling back to the
+// next one if an earlier Transport errors or doesn't finish within its
+// timeout. Pass the most-preferred Transport first (e.g. a direct LAN
+// transport before the wormhole relay) so it wins whenever it works.
+func (a *App) SetTransports(transports []Transport) {
+	a.transportMu.Lock()
+	defer a.transportMu.Unlock()
+	a.transports = transports
+}
+
+// SetTransportTimeout configures how long each Transport in the chain is
+// given to complete before falling back to the next one. 0 (the zero
+// value) means defaultTransportTimeout.
+func (a *App) SetTransportTimeout(d time.Duration) {
+	a.transportMu.Lock()
+	defer a.transportMu.Unlock()
+	a.transportTimeout = d
+}
+
+// getTransports returns the configured transport chain, defaulting to a
+// single wormholeTransport routed through relayName (matching SendFile/
+// ReceiveFile's prior direct-croc behavior) if SetTransports was never
+// called.
+func (a *App) getTransports(relayName string) []Transport {
+	a.transportMu.Lock()
+	defer a.transportMu.Unlock()
+	if len(a.transports) == 0 {
+		return []Transport{newWormholeTransport(a, relayName)}
+	}
+	return a.transports
+}
+
+func (a *App) getTransportTimeout() time.Duration {
+	a.transportMu.Lock()
+	defer a.transportMu.Unlock()
+	if a.transportTimeout <= 0 {
+		return defaultTransportTimeout
+	}
+	return a.transportTimeout
+}
+
+// drainProgress relays every Progress update from progress to onProgress
+// until it closes or ctx is done, returning the last update seen and
+// ok=true only if that last update reported success (Done with no Err).
+func drainProgress(ctx context.Context, progress <-chan Progress, onProgress func(Progress)) (last Progress, ok bool, err error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return last, false, ctx.Err()
+		case p, open := <-progress:
+			if !open {
+				return last, false, nil
+			}
+			last = p
+			if onProgress != nil {
+				onProgress(p)
+			}
+			if p.Err != nil {
+				return last, false, p.Err
+			}
+			if p.Done {
+				return last, true, nil
+			}
+		}
+	}
+}
+
+// tryTransportsForSend tries each of transports' Offer in order, giving
+// each up to timeout to both start and finish successfully - except the
+// last transport in the chain, which runs against ctx directly, since
+// there's nothing left to fall back to if it were to time out. onProgress,
+// if non-nil, is called with every Progress update from whichever Transport
+// is currently being attempted, so a caller can surface it (e.g. as a
+// Wails event) without this function needing to know about Wails. It
+// returns whichever Transport succeeded and the code it offered, or the
+// last error encountered if every Transport failed.
+func tryTransportsForSend(ctx context.Context, transports []Transport, timeout time.Duration, file string, onProgress func(Transport, Progress)) (Transport, string, error) {
+	var lastErr error
+	for i, t := range transports {
+		attemptCtx, cancel := attemptContext(ctx, timeout, i == len(transports)-1)
+		code, progress, err := t.Offer(attemptCtx, file)
+		if err != nil {
+			cancel()
+			lastErr = err
+			logrus.WithError(err).Warnf("transport %q failed to start, trying next", t.Name())
+			continue
+		}
+
+		_, ok, err := drainProgress(attemptCtx, progress, func(p Progress) {
+			if onProgress != nil {
+				onProgress(t, p)
+			}
+		})
+		cancel()
+		if ok {
+			return t, code, nil
+		}
+		lastErr = transportFailure(t, err)
+	}
+	return nil, "", lastErr
+}
+
+// tryTransportsForReceive is tryTransportsForSend for the receiving side. It
+// additionally returns the last Progress reported by whichever Transport
+// succeeded, since that's the only place a receiver can learn the sender-
+// reported file hashes (Progress.Files) once the croc client that knows
+// them is encapsulated inside wormholeTransport.
+func tryTransportsForReceive(ctx context.Context, transports []Transport, timeout time.Duration, code, destinationPath string, onProgress func(Transport, Progress)) (Transport, Progress, error) {
+	var lastErr error
+	for i, t := range transports {
+		attemptCtx, cancel := attemptContext(ctx, timeout, i == len(transports)-1)
+		progress, err := t.Accept(attemptCtx, code, destinationPath)
+		if err != nil {
+			cancel()
+			lastErr = err
+			logrus.WithError(err).Warnf("transport %q failed to start, trying next", t.Name())
+			continue
+		}
+
+		last, ok, err := drainProgress(attemptCtx, progress, func(p Progress) {
+			if onProgress != nil {
+				onProgress(t, p)
+			}
+		})
+		cancel()
+		if ok {
+			return t, last, nil
+		}
+		lastErr = transportFailure(t, err)
+	}
+	return nil, Progress{}, lastErr
+}
+
+// attemptContext derives the context one Transport attempt runs under: the
+// plain parent ctx for the last transport in a chain (nothing left to fall
+// back to, so there's no reason to fail a slow-but-working transfer early),
+// or ctx bounded by timeout for every earlier one.
+func attemptContext(ctx context.Context, timeout time.Duration, isLast bool) (context.Context, context.CancelFunc) {
+	if isLast {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func transportFailure(t Transport, err error) error {
+	if err != nil {
+		return errors.Wrapf(err, "transport %q", t.Name())
+	}
+	return errors.Errorf("transport %q timed out", t.Name())
+}
+
+// wormholeTransport is the existing croc/wormhole relay, wrapped as a
+// Transport. Unlike SendFile/ReceiveFile's direct use of croc (which also
+// handles tempDir staging, hash verification, policy checks, and preview
+// generation - see performSend/performReceive), this is a lean
+// implementation of just the Offer/Accept contract, since those richer
+// behaviors don't yet have a place in the generic Transport interface.
+type wormholeTransport struct {
+	app       *App
+	relayName string
+}
+
+// newWormholeTransport returns a Transport backed by croc, routed through
+// the named relay (or the default, for an empty relayName).
+func newWormholeTransport(app *App, relayName string) *wormholeTransport {
+	return &wormholeTransport{app: app, relayName: relayName}
+}
+
+func (t *wormholeTransport) Name() string { return "wormhole" }
+
+func (t *wormholeTransport) Offer(ctx context.Context, file string) (string, <-chan Progress, error) {
+	code := utils.GetRandomName()
+	options := t.app.buildOptions(GetConfig(ctx), t.relayName, "send", true, code)
+
+	crocClient, err := croc.New(options)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to create croc client")
+	}
+
+	filesInfo, emptyFolders, totalFolders, err := croc.GetFilesInfo([]string{file}, false, false, []string{})
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to get file info")
+	}
+
+	var totalSize int64
+	for _, f := range filesInfo {
+		totalSize += f.Size
+	}
+
+	progress := make(chan Progress)
+	go func() {
+		defer close(progress)
+
+		stop := pollCrocProgress(progress, func() (int64, int64) { return crocClient.TotalSent, totalSize })
+		defer close(stop)
+
+		if err := crocClient.Send(filesInfo, emptyFolders, totalFolders); err != nil {
+			progress <- Progress{Err: errors.Wrap(err, "failed to send via wormhole")}
+			return
+		}
+		progress <- Progress{BytesTransferred: totalSize, TotalBytes: totalSize, Done: true}
+	}()
+
+	return code, progress, nil
+}
+
+func (t *wormholeTransport) Accept(ctx context.Context, code, destinationPath string) (<-chan Progress, error) {
+	options := t.app.buildOptions(GetConfig(ctx), t.relayName, "receive", false, code)
+
+	crocClient, err := croc.New(options)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create croc client")
+	}
+
+	currentDir, err := t.app.fs.Getwd()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get current directory")
+	}
+	if err := t.app.fs.Chdir(destinationPath); err != nil {
+		return nil, errors.Wrapf(err, "failed to change directory to %s", destinationPath)
+	}
+
+	progress := make(chan Progress)
+	go func() {
+		defer close(progress)
+		defer t.app.fs.Chdir(currentDir)
+
+		stop := pollCrocProgress(progress, func() (int64, int64) {
+			var total int64
+			for _, f := range crocClient.FilesToTransfer {
+				total += f.Size
+			}
+			return crocClient.TotalSent, total
+		})
+		defer close(stop)
+
+		if err := crocClient.Receive(); err != nil {
+			progress <- Progress{Err: errors.Wrap(err, "failed to receive via wormhole")}
+			return
+		}
+
+		files := make([]TransferredFile, len(crocClient.FilesToTransfer))
+		for i, f := range crocClient.FilesToTransfer {
+			files[i] = TransferredFile{Name: f.Name, Hash: f.Hash}
+		}
+		progress <- Progress{Done: true, Files: files}
+	}()
+
+	return progress, nil
+}
+
+// pollCrocProgress polls sample at transportProgressPollInterval, sending
+// a Progress update to out each time, until the returned channel is
+// closed.
+func pollCrocProgress(out chan<- Progress, sample func() (sent, total int64)) chan struct{} {
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(transportProgressPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				sent, total := sample()
+				out <- Progress{BytesTransferred: sent, TotalBytes: total}
+			}
+		}
+	}()
+	return stop
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// mockTransport is an in-memory Transport double for testing
+// tryTransportsForSend/tryTransportsForReceive's fallback behavior without
+// any real network.
+type mockTransport struct {
+	name string
+
+	offerErr      error
+	offerDelay    time.Duration
+	offerProgress []Progress
+	offered       []string
+
+	acceptErr      error
+	acceptDelay    time.Duration
+	acceptProgress []Progress
+	accepted       []string
+}
+
+func (m *mockTransport) Name() string { return m.name }
+
+func (m *mockTransport) Offer(ctx context.Context, file string) (string, <-chan Progress, error) {
+	m.offered = append(m.offered, file)
+	if m.offerErr != nil {
+		return "", nil, m.offerErr
+	}
+
+	progress := make(chan Progress, len(m.offerProgress))
+	go func() {
+		defer close(progress)
+		if m.offerDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(m.offerDelay):
+			}
+		}
+		for _, p := range m.offerProgress {
+			progress <- p
+		}
+	}()
+	return "mock-code-" + m.name, progress, nil
+}
+
+func (m *mockTransport) Accept(ctx context.Context, code, destinationPath string) (<-chan Progress, error) {
+	m.accepted = append(m.accepted, code)
+	if m.acceptErr != nil {
+		return nil, m.acceptErr
+	}
+
+	progress := make(chan Progress, len(m.acceptProgress))
+	go func() {
+		defer close(progress)
+		if m.acceptDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(m.acceptDelay):
+			}
+		}
+		for _, p := range m.acceptProgress {
+			progress <- p
+		}
+	}()
+	return progress, nil
+}
+
+// TestTryTransportsForSend_FallsBackOnError tests that a Transport whose
+// Offer errors immediately is skipped in favor of the next one.
+func TestTryTransportsForSend_FallsBackOnError(t *testing.T) {
+	failing := &mockTransport{name: "failing", offerErr: errors.New("no peer reachable")}
+	working := &mockTransport{name: "working", offerProgress: []Progress{{Done: true}}}
+
+	transport, code, err := tryTransportsForSend(context.Background(), []Transport{failing, working}, time.Second, "file.txt", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transport.Name() != "working" {
+		t.Errorf("expected the working transport to be used, got %s", transport.Name())
+	}
+	if code != "mock-code-working" {
+		t.Errorf("expected the working transport's code, got %s", code)
+	}
+	if len(failing.offered) != 1 || len(working.offered) != 1 {
+		t.Errorf("expected both transports to be tried exactly once, got failing=%d working=%d", len(failing.offered), len(working.offered))
+	}
+}
+
+// TestTryTransportsForSend_FallsBackOnTimeout tests that a Transport whose
+// Offer succeeds but never reports completion within the timeout is
+// abandoned in favor of the next one.
+func TestTryTransportsForSend_FallsBackOnTimeout(t *testing.T) {
+	hanging := &mockTransport{name: "hanging", offerDelay: time.Second}
+	working := &mockTransport{name: "working", offerProgress: []Progress{{Done: true}}}
+
+	transport, _, err := tryTransportsForSend(context.Background(), []Transport{hanging, working}, 20*time.Millisecond, "file.txt", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transport.Name() != "working" {
+		t.Errorf("expected fallback to the working transport after the hanging one timed out, got %s", transport.Name())
+	}
+}
+
+// TestTryTransportsForSend_PrefersEarlierTransport tests that when two
+// Transports would both succeed, the first one in the list wins and the
+// rest are never tried - the behavior that lets a direct LAN transport
+// take priority over the wormhole relay when both peers are reachable
+// directly.
+func TestTryTransportsForSend_PrefersEarlierTransport(t *testing.T) {
+	lan := &mockTransport{name: "lan", offerProgress: []Progress{{Done: true}}}
+	wormhole := &mockTransport{name: "wormhole", offerProgress: []Progress{{Done: true}}}
+
+	transport, _, err := tryTransportsForSend(context.Background(), []Transport{lan, wormhole}, time.Second, "file.txt", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transport.Name() != "lan" {
+		t.Errorf("expected the first (LAN) transport to win, got %s", transport.Name())
+	}
+	if len(wormhole.offered) != 0 {
+		t.Error("expected the wormhole transport to never be tried once LAN succeeded")
+	}
+}
+
+// TestTryTransportsForSend_AllFail tests that exhausting every Transport
+// returns the last error encountered.
+func TestTryTransportsForSend_AllFail(t *testing.T) {
+	first := &mockTransport{name: "first", offerErr: errors.New("first failed")}
+	second := &mockTransport{name: "second", offerErr: errors.New("second failed")}
+
+	if _, _, err := tryTransportsForSend(context.Background(), []Transport{first, second}, time.Second, "file.txt", nil); err == nil {
+		t.Error("expected an error when every transport fails")
+	}
+}
+
+// TestTryTransportsForSend_LastTransportIgnoresTimeout tests that the final
+// transport in the chain is given the plain parent context instead of one
+// bounded by timeout, so a slow-but-working single transport (the common
+// case: no fallback configured) doesn't get cut off mid-transfer.
+func TestTryTransportsForSend_LastTransportIgnoresTimeout(t *testing.T) {
+	slow := &mockTransport{name: "slow", offerDelay: 20 * time.Millisecond, offerProgress: []Progress{{Done: true}}}
+
+	transport, _, err := tryTransportsForSend(context.Background(), []Transport{slow}, time.Millisecond, "file.txt", nil)
+	if err != nil {
+		t.Fatalf("expected the only transport to succeed despite exceeding timeout, got %v", err)
+	}
+	if transport.Name() != "slow" {
+		t.Errorf("expected the slow transport to be used, got %s", transport.Name())
+	}
+}
+
+// TestTryTransportsForReceive_FallsBackOnError mirrors
+// TestTryTransportsForSend_FallsBackOnError for the receiving side.
+func TestTryTransportsForReceive_FallsBackOnError(t *testing.T) {
+	failing := &mockTransport{name: "failing", acceptErr: errors.New("no announcement heard")}
+	working := &mockTransport{name: "working", acceptProgress: []Progress{{Done: true}}}
+
+	transport, _, err := tryTransportsForReceive(context.Background(), []Transport{failing, working}, time.Second, "some-code", "/tmp", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transport.Name() != "working" {
+		t.Errorf("expected the working transport to be used, got %s", transport.Name())
+	}
+	if len(failing.accepted) != 1 || failing.accepted[0] != "some-code" {
+		t.Errorf("expected the failing transport to be tried with the given code, got %+v", failing.accepted)
+	}
+}
+
+// TestTryTransportsForReceive_ReportsProgress tests that onProgress is
+// called with updates from the transport currently being tried.
+func TestTryTransportsForReceive_ReportsProgress(t *testing.T) {
+	working := &mockTransport{name: "working", acceptProgress: []Progress{
+		{BytesTransferred: 50, TotalBytes: 100},
+		{BytesTransferred: 100, TotalBytes: 100, Done: true},
+	}}
+
+	var seen []Progress
+	_, _, err := tryTransportsForReceive(context.Background(), []Transport{working}, time.Second, "some-code", "/tmp", func(tr Transport, p Progress) {
+		seen = append(seen, p)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(seen) != 2 {
+		t.Fatalf("expected 2 progress updates, got %d", len(seen))
+	}
+	if seen[0].BytesTransferred != 50 || seen[1].BytesTransferred != 100 {
+		t.Errorf("unexpected progress updates: %+v", seen)
+	}
+}
+
+// TestTryTransportsForReceive_ReturnsFinalProgress tests that the final
+// Progress from whichever Transport succeeded is returned, carrying the
+// sender-reported file hashes performReceive needs for verification.
+func TestTryTransportsForReceive_ReturnsFinalProgress(t *testing.T) {
+	files := []TransferredFile{{Name: "a.txt", Hash: []byte{1, 2, 3}}}
+	working := &mockTransport{name: "working", acceptProgress: []Progress{{Done: true, Files: files}}}
+
+	_, final, err := tryTransportsForReceive(context.Background(), []Transport{working}, time.Second, "some-code", "/tmp", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(final.Files) != 1 || final.Files[0].Name != "a.txt" {
+		t.Errorf("expected the final progress to carry the reported files, got %+v", final.Files)
+	}
+}